@@ -0,0 +1,112 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+)
+
+const (
+	animSpeed     = 0.2 // 每帧移动的格数
+	maxAnimFrames = 20
+	effectFrames  = 30
+)
+
+// animPos 记录棋子、石头、怪物画在屏幕上的位置，每帧向实际位置靠近一点
+type animPos struct {
+	x, y   float64
+	inited bool
+}
+
+func (a *animPos) animate(target point) {
+	a.animateTo(float64(target.x), float64(target.y))
+}
+
+// animateTo 和animate一样，但目标位置可以不在格子上
+func (a *animPos) animateTo(tx, ty float64) {
+	if !a.inited || !gameSettings.Animation {
+		a.x, a.y, a.inited = tx, ty, true
+		return
+	}
+	dx, dy := tx-a.x, ty-a.y
+	dist := math.Hypot(dx, dy)
+	speed := math.Max(animSpeed, dist/maxAnimFrames)
+	if dist <= speed {
+		a.x, a.y = tx, ty
+		return
+	}
+	a.x += dx / dist * speed
+	a.y += dy / dist * speed
+}
+
+func (a *animPos) teleport(target point) {
+	a.x, a.y, a.inited = float64(target.x), float64(target.y), true
+}
+
+func (a *animPos) drawn() (float64, float64) {
+	return a.x, a.y
+}
+
+type effectType uint8
+
+const (
+	effectTypeDeath effectType = iota
+	effectTypeExit
+)
+
+type effect struct {
+	typ   effectType
+	pos   point
+	start int
+}
+
+func (b *board) addEffect(typ effectType, pos point) {
+	if gameSettings.Animation {
+		b.effects = append(b.effects, &effect{typ: typ, pos: pos, start: b.tick})
+	}
+}
+
+func (b *board) updateAnim() {
+	for i := range b.items {
+		for j := range b.items[i] {
+			if b.items[i][j] != nil {
+				b.items[i][j].updateAnim()
+			}
+		}
+	}
+	slots := b.traySlots()
+	for _, player := range b.player {
+		for _, item := range player.items {
+			if slot, ok := slots[item]; ok {
+				item.animateTo(slot[0], slot[1])
+			} else {
+				item.animate(item.pos)
+			}
+		}
+	}
+	effects := b.effects[:0]
+	for _, e := range b.effects {
+		if b.tick-e.start < effectFrames {
+			effects = append(effects, e)
+		}
+	}
+	b.effects = effects
+}
+
+func (b *board) drawEffects(screen *ebiten.Image) {
+	g := b.geo
+	for _, e := range b.effects {
+		progress := float64(b.tick-e.start) / effectFrames
+		c := theView.theme.Death
+		if e.typ == effectTypeExit {
+			c = theView.theme.Exit
+		}
+		// 以格子中心向外扩散并逐渐变淡
+		size := float64(g.gridLen) * (0.5 + progress)
+		opt := &ebiten.DrawImageOptions{}
+		opt.GeoM.Scale(size, size)
+		opt.GeoM.Translate(g.cellX(float64(e.pos.x))+(float64(g.gridLen)-size)/2, g.cellY(float64(e.pos.y))+(float64(g.gridLen)-size)/2)
+		opt.ColorM.ScaleWithColor(c)
+		opt.ColorM.Scale(1, 1, 1, 0.6*(1-progress))
+		screen.DrawImage(emptyImage, opt)
+	}
+}
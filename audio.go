@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/hajimehoshi/oto/v2"
+	"io"
+	"sync"
+)
+
+const sampleRate = 44100
+
+type sound int
+
+const (
+	soundStep sound = iota
+	soundPush
+	soundSlide
+	soundRoar
+	soundDeath
+	soundCard
+	soundCount
+)
+
+var (
+	//go:embed assets/step.wav
+	fileStep []byte
+	//go:embed assets/push.wav
+	filePush []byte
+	//go:embed assets/slide.wav
+	fileSlide []byte
+	//go:embed assets/roar.wav
+	fileRoar []byte
+	//go:embed assets/death.wav
+	fileDeath []byte
+	//go:embed assets/card.wav
+	fileCard []byte
+	//go:embed assets/music.wav
+	fileMusic []byte
+)
+
+// audioManager 直接使用oto播放ebiten解码出来的声音。
+// ebiten的audio.Context在没有声音设备时会让RunGame返回错误，而这里只需要静音即可。
+// 声音设备在另一个goroutine中初始化，所以音量从gameSettings复制一份，和其他字段一样用lock保护
+type audioManager struct {
+	lock        sync.Mutex
+	mute        bool
+	soundVolume float64
+	musicVolume float64
+	context     *oto.Context
+	sounds      [soundCount][]byte
+	music       *audio.InfiniteLoop
+	bgm         oto.Player
+	playing     []oto.Player
+}
+
+var theAudio = &audioManager{}
+
+func initAudio() {
+	a := theAudio
+	for i, file := range [soundCount][]byte{fileStep, filePush, fileSlide, fileRoar, fileDeath, fileCard} {
+		buf, err := decodeWav(file)
+		if err != nil {
+			logger.WithError(err).Warn("decode sound failed")
+			continue
+		}
+		a.sounds[i] = buf
+	}
+	if buf, err := decodeWav(fileMusic); err != nil {
+		logger.WithError(err).Warn("decode music failed")
+	} else {
+		a.music = audio.NewInfiniteLoop(bytes.NewReader(buf), int64(len(buf)))
+	}
+	updateVolume()
+	ctx, ready, err := oto.NewContext(sampleRate, 2, 2)
+	if err != nil {
+		logger.WithError(err).Warn("no audio device, sound disabled")
+		return
+	}
+	go func() {
+		<-ready
+		if err := ctx.Err(); err != nil {
+			logger.WithError(err).Warn("no audio device, sound disabled")
+			return
+		}
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		a.context = ctx
+		if a.music != nil {
+			a.bgm = ctx.NewPlayer(a.music)
+			a.applyVolume()
+			a.bgm.Play()
+		}
+	}()
+}
+
+func decodeWav(file []byte) ([]byte, error) {
+	s, err := wav.DecodeWithSampleRate(sampleRate, bytes.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(s)
+}
+
+func playSound(s sound) {
+	a := theAudio
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.context == nil || a.sounds[s] == nil || a.mute || a.soundVolume <= 0 {
+		return
+	}
+	playing := a.playing[:0]
+	for _, p := range a.playing {
+		if p.IsPlaying() {
+			playing = append(playing, p)
+		} else {
+			_ = p.Close()
+		}
+	}
+	p := a.context.NewPlayer(bytes.NewReader(a.sounds[s]))
+	p.SetVolume(a.soundVolume)
+	p.Play()
+	a.playing = append(playing, p)
+}
+
+// updateVolume 在设置改变后调用，只能在游戏的goroutine中调用
+func updateVolume() {
+	a := theAudio
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.mute, a.soundVolume, a.musicVolume = gameSettings.Mute, gameSettings.SoundVolume, gameSettings.MusicVolume
+	a.applyVolume()
+}
+
+func (a *audioManager) applyVolume() {
+	if a.bgm == nil {
+		return
+	}
+	if a.mute {
+		a.bgm.SetVolume(0)
+	} else {
+		a.bgm.SetVolume(a.musicVolume)
+	}
+}
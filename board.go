@@ -5,55 +5,31 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
-	"golang.org/x/image/colornames"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
 	"image/color"
 	"math/rand"
+	"strconv"
 	"time"
 )
 
 //go:embed assets/FZSTK.TTF
 var ttfFile []byte
+var ttFont *opentype.Font
 var fontAlpha font.Face
 var fontNum font.Face
+var fontSmall font.Face
 var emptyImage = ebiten.NewImage(1, 1)
-var imgSlipFloor *ebiten.Image
 
 func init() {
-	imgSlipFloor = ebiten.NewImage(gridLen, gridLen)
-	imgSlipFloor.Fill(colornames.Darkred)
-	emptyImage.Fill(color.Black)
-	tt, err := opentype.Parse(ttfFile)
-	if err != nil {
-		panic(err)
-	}
-	fontAlpha, err = opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    24,
-		DPI:     72,
-		Hinting: font.HintingFull,
-	})
-	if err != nil {
-		panic(err)
-	}
-	fontNum, err = opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    48,
-		DPI:     72,
-		Hinting: font.HintingFull,
-	})
+	emptyImage.Fill(color.White)
+	var err error
+	ttFont, err = opentype.Parse(ttfFile)
 	if err != nil {
 		panic(err)
 	}
 }
 
-const (
-	edgeX   = 50
-	edgeY   = 100
-	width   = 15
-	height  = 10
-	gridLen = 60
-)
-
 type dir point
 
 var (
@@ -75,10 +51,12 @@ const (
 )
 
 type board struct {
+	geo                   *geometry
+	rules                 *rules
 	items                 [][]itemInterface
 	itemsCache            [][]itemInterface
 	floorShape            [][]floorShapeType
-	monster               *monster
+	monsters              []*monster
 	player                []*player
 	random                *rand.Rand
 	pickedPlayerItem      *playerItem
@@ -88,42 +66,96 @@ type board struct {
 	smallTurn             int
 	bigTurn               int
 	alreadyMoveCount      int
+	tick                  int
+	showInfo              bool
+	explain               bool
+	effects               []*effect
+	// mode 单人模式下记录成绩，daily是每日挑战的日期
+	mode       gameMode
+	daily      string
+	score      *soloScore
+	scoreRank  int
+	scoreTable []*soloScore
+	// puzzle 不为nil时是残局，怪物走完一次后结束
+	puzzle       *puzzle
+	puzzleDone   bool
+	puzzleFailed []string
+	bestSolution *puzzleSolution
+	// moves 这一局每个棋子依次走的方向，例如A1:→→↓，用于记录残局的解法。path是选中的棋子已经走的方向
+	moves    []string
+	path     string
+	tutorial *tutorial
 }
 
-func newBoard(playerNum int) *board {
+func newEmptyBoard(geo *geometry) *board {
 	b := &board{
-		items:      make([][]itemInterface, height),
-		itemsCache: make([][]itemInterface, height),
-		floorShape: make([][]floorShapeType, height),
-		player:     make([]*player, playerNum),
+		geo:        geo,
+		rules:      rulesPresets()[0],
+		items:      make([][]itemInterface, geo.Height),
+		itemsCache: make([][]itemInterface, geo.Height),
+		floorShape: make([][]floorShapeType, geo.Height),
 		random:     rand.New(rand.NewSource(time.Now().UnixMilli())),
-		monster:    newMonster(),
-	}
-	for i := 0; i < height; i++ {
-		b.items[i] = make([]itemInterface, width)
-		b.itemsCache[i] = make([]itemInterface, width)
-		b.floorShape[i] = make([]floorShapeType, width)
-	}
-	for i := 0; i < 11; i++ {
-		(&stoneRegular{}).init(b)
-	}
-	b.initSlipFloor()
-	switch len(b.player) {
-	case 4:
-		b.player[3] = newPlayer(colornames.Blue, "蓝方")
-		fallthrough
-	case 3:
-		b.player[2] = newPlayer(colornames.Yellow, "黄方")
-		fallthrough
-	case 2:
-		b.player[1] = newPlayer(colornames.Green, "绿方")
-		fallthrough
-	case 1:
-		b.player[0] = newPlayer(colornames.Red, "红方")
-		b.player[0].display(b)
-	default:
+		monsters:   []*monster{newMonster(geo)},
+	}
+	for i := 0; i < geo.Height; i++ {
+		b.items[i] = make([]itemInterface, geo.Width)
+		b.itemsCache[i] = make([]itemInterface, geo.Width)
+		b.floorShape[i] = make([]floorShapeType, geo.Width)
+	}
+	return b
+}
+
+// newBoard 如果有布局文件，则使用布局文件中的棋盘形状，忽略o.geo。
+// 每日挑战总是用默认大小的棋盘和随机布局，不受-width等参数和布局文件的影响
+func newBoard(o *gameOptions) *board {
+	geo, l := o.geo, o.layout
+	if o.mode == modeDaily {
+		geo, l = newGeometry(15, 10, 3), nil
+	}
+	if l != nil {
+		geo = &l.Geometry
+	}
+	b := newEmptyBoard(geo)
+	b.rules = o.rules
+	b.mode = o.mode
+	if o.mode == modeDaily {
+		b.daily = dailyDate()
+		b.random = rand.New(rand.NewSource(dailySeed(b.daily)))
+	}
+	b.monsters[0].deck = newDeck(b.random, b.rules.Deck)
+	b.player = make([]*player, o.playerNum)
+	if l != nil {
+		if err := b.applyLayout(l); err != nil {
+			logger.Fatal(err)
+		}
+	} else {
+		for i := 0; i < b.rules.StoneCount; i++ {
+			(&stoneRegular{}).init(b)
+		}
+		b.initSlipFloor()
+	}
+	if o.playerNum < 1 || o.playerNum > maxPlayers {
 		logger.Fatal("invalid player number")
 	}
+	// 布局里的怪物位置固定，不够o.monsterNum个时其余的放在随机的空地上
+	for _, m := range b.monsters[1:] {
+		if !o.sharedDeck {
+			m.pile = &pile{deck: newDeck(b.random, b.rules.Deck)}
+		}
+	}
+	for len(b.monsters) < o.monsterNum {
+		if !b.addMonster(o.sharedDeck) {
+			break
+		}
+	}
+	if o.brain != nil {
+		for _, m := range b.monsters {
+			m.brain = o.brain
+		}
+	}
+	for i := range b.player {
+		b.player[i] = newPlayer(b, i, tr(playerNames[i]))
+	}
 	return b
 }
 
@@ -147,84 +179,91 @@ func (b *board) loadCache() {
 }
 
 func (b *board) Update() error {
-	if b.monster.isMoving {
+	b.tick++
+	if isJustPressed(actionMute) {
+		gameSettings.Mute = !gameSettings.Mute
+		gameSettings.save()
+		updateVolume()
+	}
+	if isJustPressed(actionInfo) {
+		b.showInfo = !b.showInfo
+	}
+	if isJustPressed(actionExplain) {
+		b.explain = !b.explain
+	}
+	if isJustPressed(actionDump) {
+		logger.Info("board dump\n" + b.dump())
+	}
+	b.updateAnim()
+	for _, m := range b.monsters {
+		m.update(b)
+	}
+	b.startNextMonster()
+	if b.monsterBusy() {
 		return nil
 	}
-	if b.pickedPlayerItem == nil {
-		if inpututil.IsKeyJustPressed(ebiten.KeyDigit1) {
-			item := b.player[b.curPlayer].willMove(1)
-			if item != nil {
-				b.pickedPlayerItem = item
-				b.saveCache()
-			}
-		} else if inpututil.IsKeyJustPressed(ebiten.KeyDigit2) {
-			item := b.player[b.curPlayer].willMove(2)
-			if item != nil {
-				b.pickedPlayerItem = item
-				b.saveCache()
-			}
-		} else if inpututil.IsKeyJustPressed(ebiten.KeyDigit3) {
-			item := b.player[b.curPlayer].willMove(3)
-			if item != nil {
-				b.pickedPlayerItem = item
-				b.saveCache()
-			}
-		} else if inpututil.IsKeyJustPressed(ebiten.KeyDigit4) {
-			item := b.player[b.curPlayer].willMove(4)
-			if item != nil {
-				b.pickedPlayerItem = item
-				b.saveCache()
+	b.checkPuzzle()
+	if b.gameOver() {
+		b.recordScore()
+		if b.puzzle != nil && isJustPressed(actionConfirm) {
+			*b = *newPuzzleBoard(b.puzzle)
+		}
+		return nil
+	}
+	if b.pickedPlayerItem == nil || b.alreadyMoveCount == 0 {
+		// 还没走的时候可以换一个棋子
+		var item *playerItem
+		for step := 1; step <= 6 && item == nil; step++ {
+			if isJustPressed(actionPiece(step)) {
+				item = b.player[b.curPlayer].willMove(b, step, b.pickedPlayerItem)
 			}
-		} else if inpututil.IsKeyJustPressed(ebiten.KeyDigit5) {
-			item := b.player[b.curPlayer].willMove(5)
-			if item != nil {
-				b.pickedPlayerItem = item
-				b.saveCache()
+		}
+		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			for _, clicked := range b.piecesAt(ebiten.CursorPosition()) {
+				if b.player[b.curPlayer].owns(clicked) && clicked.canMove(b) && b.tutorial.allowsPiece(clicked) {
+					item = clicked
+					break
+				}
 			}
-		} else if inpututil.IsKeyJustPressed(ebiten.KeyDigit6) {
-			item := b.player[b.curPlayer].willMove(6)
-			if item != nil {
-				b.pickedPlayerItem = item
-				b.saveCache()
+		}
+		if isJustPressed(actionNextPiece) {
+			item = b.player[b.curPlayer].cycle(b, b.pickedPlayerItem, 1)
+		} else if isJustPressed(actionPrevPiece) {
+			item = b.player[b.curPlayer].cycle(b, b.pickedPlayerItem, -1)
+		}
+		if item != nil && item != b.pickedPlayerItem {
+			if b.pickedPlayerItem != nil {
+				b.loadCache()
 			}
+			b.pickedPlayerItem = item
+			b.path = ""
+			b.saveCache()
+			return nil
 		}
-	} else {
-		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+	}
+	if b.pickedPlayerItem != nil {
+		if isJustPressed(actionCancel) {
 			b.loadCache()
 			b.alreadyMoveCount = 0
 			b.pickedPlayerItem = nil
-		} else if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
-			if b.pickedPlayerItem.checkLegal(b) {
+			b.path = ""
+		} else if isJustPressed(actionConfirm) {
+			if b.pickedPlayerItem.checkLegal(b) && b.tutorial.accepts(b.pickedPlayerItem) {
+				logger.WithField("piece", b.pickedPlayerItem.id).WithField("pos", b.pickedPlayerItem.pos).Info("piece moved")
+				b.moves = append(b.moves, b.pickedPlayerItem.id+":"+b.path)
+				b.path = ""
 				b.pickedPlayerItem.alreadyMove = true
 				b.alreadyMoveCount = 0
 				b.pickedPlayerItem = nil
-				for {
-					b.curPlayer = (b.curPlayer + 1) % len(b.player)
-					if b.curPlayer == b.firstPlayer {
-						b.smallTurn++
-						if b.bigTurn == 0 && b.smallTurn >= 2 || b.smallTurn >= len(b.player[0].items) {
-							b.monster.move(b)
-							for _, player := range b.player {
-								player.nextTurn()
-							}
-							b.bigTurn++
-							b.firstPlayer = (b.firstPlayer + 1) % len(b.player)
-							b.curPlayer = b.firstPlayer
-							b.smallTurn = 0
-						}
-					}
-					b.player[b.curPlayer].display(b)
-					if b.player[b.curPlayer].hasItemToMove() {
-						break
-					}
-				}
+				b.nextPlayer()
 			}
 		} else {
 			if b.alreadyMoveCount < b.pickedPlayerItem.step {
-				if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) && b.pickedPlayerItem.tryMove(b, down) ||
-					inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) && b.pickedPlayerItem.tryMove(b, left) ||
-					inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) && b.pickedPlayerItem.tryMove(b, up) ||
-					inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) && b.pickedPlayerItem.tryMove(b, right) {
+				if isJustPressed(actionDown) && b.stepPicked(down) ||
+					isJustPressed(actionLeft) && b.stepPicked(left) ||
+					isJustPressed(actionUp) && b.stepPicked(up) ||
+					isJustPressed(actionRight) && b.stepPicked(right) {
+					playSound(soundStep)
 					b.alreadyMoveCount++
 				}
 			}
@@ -233,156 +272,248 @@ func (b *board) Update() error {
 	return nil
 }
 
+// nextPlayer 轮到下一个还有棋子能移动的玩家。第一轮每个玩家走两个棋子，以后所有棋子都走完后怪物移动，开始新的一轮。
+// 每个玩家的棋子数可以不一样，没有棋子能移动的玩家会被跳过
+func (b *board) nextPlayer() {
+	for !b.gameOver() {
+		b.curPlayer = (b.curPlayer + 1) % len(b.player)
+		if b.curPlayer == b.firstPlayer {
+			b.smallTurn++
+		}
+		if b.bigTurn == 0 && b.curPlayer == b.firstPlayer && b.smallTurn >= 2 || !b.hasItemToMove() {
+			for _, m := range b.monsters {
+				m.waiting, m.round = true, b.bigTurn
+			}
+			b.startNextMonster()
+			for _, player := range b.player {
+				player.nextTurn(b)
+			}
+			b.bigTurn++
+			b.firstPlayer = (b.firstPlayer + 1) % len(b.player)
+			b.curPlayer = b.firstPlayer
+			b.smallTurn = 0
+			if b.puzzle != nil {
+				// 残局只有一轮，怪物走完后检查目标
+				return
+			}
+		}
+		if b.player[b.curPlayer].hasItemToMove(b) {
+			return
+		}
+	}
+}
+
+func (b *board) hasItemToMove() bool {
+	for _, p := range b.player {
+		if p.hasItemToMove(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// gameOver 第二副怪物牌用完，或者所有棋子都逃出或死亡时游戏结束
+func (b *board) gameOver() bool {
+	if b.puzzle != nil {
+		return b.puzzleDone
+	}
+	if b.cycle() >= 2 {
+		return true
+	}
+	for _, p := range b.player {
+		for _, item := range p.items {
+			if !item.isFinished(b) && !item.isDead(b) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// winners 返回逃出棋子最多的玩家
+func (b *board) winners() []*player {
+	var winners []*player
+	most := -1
+	for _, p := range b.player {
+		finished, _ := p.countFinished(b)
+		if finished > most {
+			most = finished
+			winners = nil
+		}
+		if finished == most {
+			winners = append(winners, p)
+		}
+	}
+	return winners
+}
+
 func (b *board) Draw(screen *ebiten.Image) {
-	screen.Fill(color.White)
-	for i := 0; i < width; i++ {
-		for j := 0; j < height; j++ {
-			if b.floorShape[j][i] == floorShapeTypeSlipFloor {
-				opt := &ebiten.DrawImageOptions{}
-				opt.GeoM.Translate(edgeX+1+float64(i)*gridLen, edgeY+1+float64(j)*gridLen)
-				screen.DrawImage(imgSlipFloor, opt)
+	b.drawBoard(screen)
+	b.drawCardPanel(screen)
+	b.drawHUD(screen)
+	if b.explain {
+		b.drawExplain(screen)
+	}
+	if b.puzzle != nil {
+		b.drawPuzzle(screen)
+	}
+	if b.showInfo {
+		b.drawPieceInfo(screen)
+	} else {
+		b.drawTooltip(screen)
+	}
+}
+
+func (b *board) drawBoard(screen *ebiten.Image) {
+	t := theView.theme
+	screen.Fill(t.Background)
+	g := b.geo
+	for i := 0; i < g.Width; i++ {
+		for j := 0; j < g.Height; j++ {
+			var img *ebiten.Image
+			switch b.floorShape[j][i] {
+			case floorShapeTypeSlipFloor:
+				img = t.imgSlipFloor
+			case floorShapeTypeTransferUp:
+				img = t.imgTransfer
+			default:
+				continue
 			}
+			opt := &ebiten.DrawImageOptions{}
+			opt.GeoM.Scale(g.scale(), g.scale())
+			b.drawAt(screen, img, opt, float64(i), float64(j))
 		}
 	}
-	for i := 0; i < width; i++ {
-		for j := 0; j < height; j++ {
+	for i := 0; i < g.Width; i++ {
+		for j := 0; j < g.Height; j++ {
 			if b.items[j][i] != nil {
-				img, opt := b.items[j][i].Draw()
-				opt.GeoM.Translate(edgeX+1+float64(i)*gridLen, edgeY+1+float64(j)*gridLen)
-				screen.DrawImage(img, opt)
+				img, opt := b.items[j][i].Draw(g)
+				x, y := b.items[j][i].drawn()
+				b.drawAt(screen, img, opt, x, y)
 			}
 		}
 	}
+	b.drawTrays(screen)
 	for _, player := range b.player {
 		for _, item := range player.items {
-			img, opt := item.Draw()
-			opt.GeoM.Translate(edgeX+1+float64(item.pos.x)*gridLen, edgeY+1+float64(item.pos.y)*gridLen)
-			screen.DrawImage(img, opt)
+			img, opt := item.Draw(b)
+			x, y := item.drawn()
+			b.drawAt(screen, img, opt, x, y)
+		}
+	}
+	if p := b.pickedPlayerItem; p != nil {
+		x, y, size := b.pieceRect(p)
+		drawFrame(screen, x, y, size, size, ui(3), t.Highlight)
+		text.Draw(screen, p.id, fontSmall, int(x+ui(4)), int(y+size-ui(4)), t.Highlight)
+	}
+	for _, m := range b.monsters {
+		img, opt := m.Draw(g)
+		x, y := m.drawn()
+		b.drawAt(screen, img, opt, x, y)
+		if gameSettings.HighContrast {
+			drawFrame(screen, g.cellX(x), g.cellY(y), float64(g.gridLen), float64(g.gridLen), ui(4), t.Death)
+		}
+		if len(b.monsters) > 1 {
+			text.Draw(screen, strconv.Itoa(m.index+1), fontSmall, int(g.cellX(x)+ui(4)), int(g.cellY(y)+ui(20)), t.Death)
 		}
 	}
-	img, opt := b.monster.Draw()
+	b.drawEffects(screen)
+	b.drawGrid(screen)
+}
+
+func (b *board) drawAt(screen, img *ebiten.Image, opt *ebiten.DrawImageOptions, x, y float64) {
+	opt.GeoM.Translate(b.geo.cellX(x), b.geo.cellY(y))
 	screen.DrawImage(img, opt)
-	for i := 0; i <= width; i++ {
-		textX := edgeX + gridLen*i + gridLen/2 - 6
-		textUp, textDown := string(rune('A'+i)), string(rune('A'+14-i))
-		opt := &ebiten.DrawImageOptions{}
-		if i < 3 {
-			text.Draw(screen, textUp, fontAlpha, textX, edgeY-3, color.Black)
-			text.Draw(screen, textDown, fontAlpha, textX, edgeY+gridLen*(height-3+i)+17, color.Black)
-			opt.GeoM.Scale(2, gridLen*(height-3+float64(i))+2)
-			opt.GeoM.Translate(edgeX+gridLen*float64(i), edgeY)
-		} else if i >= width-3 {
-			if i < width {
-				text.Draw(screen, textUp, fontAlpha, textX, edgeY+gridLen*(i-width+4)-3, color.Black)
-				text.Draw(screen, textDown, fontAlpha, textX, edgeY+gridLen*height+17, color.Black)
+}
+
+func (b *board) drawGrid(screen *ebiten.Image) {
+	g := b.geo
+	t := theView.theme
+	lw := uiInt(2)
+	line := func(x, y, w, h int) {
+		drawRect(screen, float64(x), float64(y), float64(w), float64(h), t.Grid)
+	}
+	for i := 0; i < g.Width; i++ {
+		for j := 0; j < g.Height; j++ {
+			if g.outOfRange(point{i, j}) {
+				continue
 			}
-			opt.GeoM.Scale(2, gridLen*(width+height-3-float64(i))+2)
-			opt.GeoM.Translate(edgeX+gridLen*float64(i), edgeY+gridLen*(float64(i)-width+3))
-		} else {
-			text.Draw(screen, textUp, fontAlpha, textX, edgeY-3, color.Black)
-			text.Draw(screen, textDown, fontAlpha, textX, edgeY+gridLen*height+17, color.Black)
-			opt.GeoM.Scale(2, gridLen*height+2)
-			opt.GeoM.Translate(edgeX+gridLen*float64(i), edgeY)
-		}
-		screen.DrawImage(emptyImage, opt)
-	}
-	for j := 0; j <= height; j++ {
-		textY := edgeY + gridLen*j + gridLen/2 + 6
-		textLeft, textRight := string(rune('Z'-j)), string(rune('Z'-9+j))
-		opt := &ebiten.DrawImageOptions{}
-		if j < 3 {
-			text.Draw(screen, textLeft, fontAlpha, edgeX-20, textY, color.Black)
-			text.Draw(screen, textRight, fontAlpha, edgeX+gridLen*(width-3+j)+6, textY, color.Black)
-			opt.GeoM.Scale(gridLen*(width-3+float64(j))+2, 2)
-			opt.GeoM.Translate(edgeX, edgeY+gridLen*float64(j))
-		} else if j >= height-3 {
-			if j < height {
-				text.Draw(screen, textLeft, fontAlpha, edgeX+gridLen*(j+4-height)-20, textY, color.Black)
-				text.Draw(screen, textRight, fontAlpha, edgeX+gridLen*width+6, textY, color.Black)
+			x, y := g.edgeX+g.gridLen*i, g.edgeY+g.gridLen*j
+			line(x, y, g.gridLen+lw, lw)
+			line(x, y, lw, g.gridLen+lw)
+			if g.outOfRange(point{i, j + 1}) {
+				line(x, y+g.gridLen, g.gridLen+lw, lw)
 			}
-			opt.GeoM.Scale(gridLen*(width+height-3-float64(j))+2, 2)
-			opt.GeoM.Translate(edgeX+gridLen*(float64(j)-height+3), edgeY+gridLen*float64(j))
-		} else {
-			text.Draw(screen, textLeft, fontAlpha, edgeX-20, textY, color.Black)
-			text.Draw(screen, textRight, fontAlpha, edgeX+gridLen*width+6, textY, color.Black)
-			opt.GeoM.Scale(gridLen*width+2, 2)
-			opt.GeoM.Translate(edgeX, edgeY+gridLen*float64(j))
+			if g.outOfRange(point{i + 1, j}) {
+				line(x+g.gridLen, y, lw, g.gridLen+lw)
+			}
+		}
+	}
+	for i := 0; i < g.Width; i++ {
+		top, bottom := 0, g.Height-1
+		for g.outOfRange(point{i, top}) {
+			top++
+		}
+		for g.outOfRange(point{i, bottom}) {
+			bottom--
 		}
-		screen.DrawImage(emptyImage, opt)
+		textX := g.edgeX + g.gridLen*i + g.gridLen/2 - uiInt(6)
+		text.Draw(screen, g.colLabel(i), fontAlpha, textX, g.edgeY+g.gridLen*top-uiInt(3), t.Text)
+		text.Draw(screen, g.colLabel(g.Width-1-i), fontAlpha, textX, g.edgeY+g.gridLen*(bottom+1)+uiInt(17), t.Text)
+	}
+	for j := 0; j < g.Height; j++ {
+		left, right := 0, g.Width-1
+		for g.outOfRange(point{left, j}) {
+			left++
+		}
+		for g.outOfRange(point{right, j}) {
+			right--
+		}
+		textY := g.edgeY + g.gridLen*j + g.gridLen/2 + uiInt(6)
+		text.Draw(screen, g.rowLabel(j), fontAlpha, g.edgeX+g.gridLen*left-uiInt(20), textY, t.Text)
+		text.Draw(screen, g.rowLabel(g.Height-1-j), fontAlpha, g.edgeX+g.gridLen*(right+1)+uiInt(6), textY, t.Text)
 	}
 }
 
-func (b *board) Layout(int, int) (screenWidth, screenHeight int) {
-	return 1024, 768
+func (b *board) Layout(outsideWidth, outsideHeight int) (int, int) {
+	w, h := layoutView(outsideWidth, outsideHeight)
+	b.geo.init()
+	return w, h
 }
 
 func (b *board) initSlipFloor() {
-	for {
-		x, y := b.random.Intn(width), b.random.Intn(height)
-		if x < 3 && y < 3 || x >= width-2 && y >= height-2 || x > width-2 || y > height-2 || y-x >= height-4 || x-y >= width-4 {
-			continue
-		}
-		if b.items[y][x] != nil || b.items[y+1][x] != nil || b.items[y][x+1] != nil || b.items[y+1][x+1] != nil {
-			continue
-		}
-		if b.floorShape[y][x] != floorShapeTypeEmpty || b.floorShape[y+1][x] != floorShapeTypeEmpty {
+	b.placeSlipFloor([]point{{0, 0}, {1, 0}, {0, 1}, {1, 1}})
+	if b.random.Intn(2) == 0 {
+		b.placeSlipFloor([]point{{0, 0}, {1, 0}, {2, 0}, {3, 0}})
+	} else {
+		b.placeSlipFloor([]point{{0, 0}, {0, 1}, {0, 2}, {0, 3}})
+	}
+}
+
+func (b *board) placeSlipFloor(shape []point) {
+	for tries := 0; tries < 1000; tries++ {
+		x, y := b.random.Intn(b.geo.Width), b.random.Intn(b.geo.Height)
+		if b.geo.nearEntrance(point{x, y}) || !b.canPlaceSlipFloor(x, y, shape) {
 			continue
 		}
-		if b.floorShape[y][x+1] != floorShapeTypeEmpty || b.floorShape[y+1][x+1] != floorShapeTypeEmpty {
-			continue
+		for _, p := range shape {
+			b.floorShape[y+p.y][x+p.x] = floorShapeTypeSlipFloor
 		}
-		b.floorShape[y][x] = floorShapeTypeSlipFloor
-		b.floorShape[y+1][x] = floorShapeTypeSlipFloor
-		b.floorShape[y][x+1] = floorShapeTypeSlipFloor
-		b.floorShape[y+1][x+1] = floorShapeTypeSlipFloor
-		break
+		return
 	}
-	for {
-		if b.random.Intn(2) == 0 {
-			x, y := b.random.Intn(width), b.random.Intn(height)
-			if x < 3 && y < 3 || x >= width-4 && y >= height-1 || x > width-4 || y-x >= height-3 || x-y >= width-6 {
-				continue
-			}
-			if b.items[y][x] != nil || b.items[y][x+1] != nil || b.items[y][x+2] != nil || b.items[y][x+3] != nil {
-				continue
-			}
-			if b.floorShape[y][x] != floorShapeTypeEmpty || b.floorShape[y][x+1] != floorShapeTypeEmpty {
-				continue
-			}
-			if b.floorShape[y][x+2] != floorShapeTypeEmpty || b.floorShape[y][x+3] != floorShapeTypeEmpty {
-				continue
-			}
-			b.floorShape[y][x] = floorShapeTypeSlipFloor
-			b.floorShape[y][x+1] = floorShapeTypeSlipFloor
-			b.floorShape[y][x+2] = floorShapeTypeSlipFloor
-			b.floorShape[y][x+3] = floorShapeTypeSlipFloor
-		} else {
-			x, y := b.random.Intn(width), b.random.Intn(height)
-			if x < 3 && y < 3 || x >= width-1 && y >= height-4 || y > height-4 || y-x >= height-6 || x-y >= width-3 {
-				continue
-			}
-			if b.items[y][x] != nil || b.items[y+1][x] != nil || b.items[y+2][x] != nil || b.items[y+3][x] != nil {
-				continue
-			}
-			if b.floorShape[y][x] != floorShapeTypeEmpty || b.floorShape[y+1][x] != floorShapeTypeEmpty {
-				continue
-			}
-			if b.floorShape[y+2][x] != floorShapeTypeEmpty || b.floorShape[y+3][x] != floorShapeTypeEmpty {
-				continue
-			}
-			b.floorShape[y][x] = floorShapeTypeSlipFloor
-			b.floorShape[y+1][x] = floorShapeTypeSlipFloor
-			b.floorShape[y+2][x] = floorShapeTypeSlipFloor
-			b.floorShape[y+3][x] = floorShapeTypeSlipFloor
+	logger.Warn("cannot find a place for slip floor")
+}
+
+func (b *board) canPlaceSlipFloor(x, y int, shape []point) bool {
+	for _, p := range shape {
+		pos := point{x + p.x, y + p.y}
+		if b.geo.outOfRange(pos) || pos == b.geo.Exit || b.items[pos.y][pos.x] != nil || b.floorShape[pos.y][pos.x] != floorShapeTypeEmpty {
+			return false
 		}
-		break
 	}
+	return true
 }
 
 type point struct {
 	x, y int
 }
-
-func (p point) outOfRange() bool {
-	return p.x < 0 || p.x >= width || p.y < 0 || p.y >= height || p.x-p.y >= width-3 || p.y-p.x >= height-3
-}
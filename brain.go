@@ -0,0 +1,180 @@
+package main
+
+// monsterBrain 决定怪物每走一步之后朝哪个方向，d中已经记录了四个方向上看到的东西，需要填上原因
+type monsterBrain interface {
+	Name() string
+	chooseDir(m *monster, b *board, d *decision) dir
+}
+
+var monsterBrains = []monsterBrain{classicBrain{}, hunterBrain{}, randomBrain{}, adversarialBrain{}}
+
+// classicBrain 规则书中的怪物：转向看得到的最近的棋子，有几个方向一样近或者都看不到时保持原来的方向
+type classicBrain struct{}
+
+func (classicBrain) Name() string {
+	return "经典"
+}
+
+func (classicBrain) chooseDir(m *monster, _ *board, d *decision) dir {
+	min := 99
+	for _, s := range d.sights {
+		if s.distance < min {
+			min = s.distance
+		}
+	}
+	var nearest []dir
+	for i, dd := range decisionDirs {
+		if d.sights[i].distance == min {
+			nearest = append(nearest, dd)
+		}
+	}
+	switch {
+	case min == 99:
+		d.reason = reasonNothingSeen
+	case len(nearest) > 1:
+		d.reason = reasonTie
+	case nearest[0] == m.faceTo:
+		d.reason = reasonAhead
+	default:
+		d.reason = reasonTurn
+		return nearest[0]
+	}
+	return m.faceTo
+}
+
+// hunterBrain 只追领先玩家的棋子，看不到领先玩家的棋子时和经典规则一样
+type hunterBrain struct{}
+
+func (hunterBrain) Name() string {
+	return "猎手"
+}
+
+func (hunterBrain) chooseDir(m *monster, b *board, d *decision) dir {
+	leader := b.leader()
+	min, best := 99, m.faceTo
+	tie := false
+	for i, dd := range decisionDirs {
+		if d.sights[i].behind {
+			continue
+		}
+		for _, cell := range b.lookAlong(m.pos, dd) {
+			if !cell.hasPieceOf(leader) {
+				continue
+			}
+			if cell.distance < min {
+				min, best, tie = cell.distance, dd, false
+			} else if cell.distance == min {
+				tie = true
+			}
+			break
+		}
+	}
+	if min == 99 || tie {
+		return classicBrain{}.chooseDir(m, b, d)
+	}
+	d.reason, d.target = reasonHunt, leader.text
+	return best
+}
+
+// randomBrain 每一步随便选一个方向，但不会回头，适合和小朋友玩
+type randomBrain struct{}
+
+func (randomBrain) Name() string {
+	return "随机"
+}
+
+func (randomBrain) chooseDir(m *monster, b *board, d *decision) dir {
+	var dirs []dir
+	for i, dd := range decisionDirs {
+		if !d.sights[i].behind {
+			dirs = append(dirs, dd)
+		}
+	}
+	d.reason = reasonRandom
+	return dirs[b.random.Intn(len(dirs))]
+}
+
+// adversarialBrain 选择这张牌剩下的步数内能吃掉领先玩家最多棋子的方向，一样多时选更近的
+type adversarialBrain struct{}
+
+func (adversarialBrain) Name() string {
+	return "针对领先者"
+}
+
+func (adversarialBrain) chooseDir(m *monster, b *board, d *decision) dir {
+	leader := b.leader()
+	reach := m.leftStep
+	if reach == 0 {
+		reach = 99
+	}
+	bestScore, bestDistance, best := 0, 99, m.faceTo
+	for i, dd := range decisionDirs {
+		if d.sights[i].behind {
+			continue
+		}
+		score, nearest := 0, 99
+		for _, cell := range b.lookAlong(m.pos, dd) {
+			if cell.distance > reach || !cell.hasPieceOf(leader) {
+				continue
+			}
+			for _, item := range cell.items {
+				if leader.owns(item) {
+					score++
+				}
+			}
+			if cell.distance < nearest {
+				nearest = cell.distance
+			}
+		}
+		if score > bestScore || score == bestScore && score > 0 && nearest < bestDistance {
+			bestScore, bestDistance, best = score, nearest, dd
+		}
+	}
+	if bestScore == 0 {
+		return classicBrain{}.chooseDir(m, b, d)
+	}
+	d.reason, d.target = reasonAdversary, leader.text
+	return best
+}
+
+// leader 逃出棋子最多的玩家，一样多时比较死亡的棋子，再一样时取先行动的玩家
+func (b *board) leader() *player {
+	var leader *player
+	bestFinished, bestDead := -1, 0
+	for _, p := range b.player {
+		finished, dead := p.countFinished(b)
+		if finished > bestFinished || finished == bestFinished && dead < bestDead {
+			leader, bestFinished, bestDead = p, finished, dead
+		}
+	}
+	return leader
+}
+
+// lineCell 怪物沿着一个方向看过去时，某个有棋子的格子
+type lineCell struct {
+	distance int
+	items    []*playerItem
+}
+
+func (c lineCell) hasPieceOf(p *player) bool {
+	for _, item := range c.items {
+		if p.owns(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookAlong 从pos沿着d方向一直看到石头或棋盘边缘，返回路上所有有棋子的格子
+func (b *board) lookAlong(pos point, d dir) []lineCell {
+	var cells []lineCell
+	for i := 1; ; i++ {
+		pos = point{pos.x + d.x, pos.y + d.y}
+		if b.geo.outOfRange(pos) || b.items[pos.y][pos.x] != nil || b.monsterAt(pos) != nil {
+			return cells
+		}
+		if items := b.piecesOn(pos); len(items) > 0 {
+			cells = append(cells, lineCell{i, items})
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+type cardEffect string
+
+const (
+	cardEffectNone cardEffect = ""
+	cardEffectTurn cardEffect = "turn"
+	cardEffectJump cardEffect = "jump"
+)
+
+type card struct {
+	Text   string     `json:"text"`
+	Step   int        `json:"step"`
+	Kills  int        `json:"kills"`
+	Effect cardEffect `json:"effect,omitempty"`
+}
+
+func defaultDeck() []*card {
+	return []*card{
+		{"5", 5, 99, cardEffectNone},
+		{"7", 7, 99, cardEffectNone},
+		{"7", 7, 99, cardEffectNone},
+		{"8", 8, 99, cardEffectNone},
+		{"8", 8, 99, cardEffectNone},
+		{"10", 10, 99, cardEffectNone},
+		{"X", 20, 1, cardEffectNone},
+		{"XX", 20, 2, cardEffectNone},
+	}
+}
+
+// pile 怪物的牌堆和弃牌堆，几个怪物可以共用一个
+type pile struct {
+	deck    []*card
+	discard []*card
+	// cycle 牌堆已经用完的次数，用完一次后进入第二阶段，用完两次游戏结束
+	cycle int
+}
+
+// pileJSON 牌堆保存时的格式，牌堆的字段都不导出
+type pileJSON struct {
+	Deck    []*card `json:"deck"`
+	Discard []*card `json:"discard"`
+	Cycle   int     `json:"cycle"`
+}
+
+func (p *pile) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&pileJSON{Deck: p.deck, Discard: p.discard, Cycle: p.cycle})
+}
+
+func (p *pile) UnmarshalJSON(buf []byte) error {
+	var v pileJSON
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	if v.Cycle < 0 || v.Cycle > 2 {
+		return fmt.Errorf("invalid cycle: %d", v.Cycle)
+	}
+	p.deck, p.discard, p.cycle = v.Deck, v.Discard, v.Cycle
+	return nil
+}
+
+// newDeck 复制一份牌并洗好，m.deck[0]是最上面的一张
+func newDeck(r *rand.Rand, cards []*card) []*card {
+	deck := append([]*card(nil), cards...)
+	shuffleDeck(r, deck)
+	return deck
+}
+
+func shuffleDeck(r *rand.Rand, deck []*card) {
+	r.Shuffle(len(deck), func(i, j int) {
+		deck[i], deck[j] = deck[j], deck[i]
+	})
+}
+
+// buryCards 把步数大于等于step的牌按原来的顺序放到牌堆底下
+func buryCards(deck []*card, step int) []*card {
+	var top, bottom []*card
+	for _, c := range deck {
+		if c.Step >= step {
+			bottom = append(bottom, c)
+		} else {
+			top = append(top, c)
+		}
+	}
+	return append(top, bottom...)
+}
+
+func validateDeck(cards []*card) error {
+	if len(cards) < 2 {
+		return errors.New(tr("牌堆至少需要2张牌"))
+	}
+	for _, c := range cards {
+		if c.Step <= 0 || c.Kills <= 0 {
+			return fmt.Errorf(tr("牌%s的步数和杀人数必须大于0"), c.Text)
+		}
+		if c.Effect != cardEffectNone && c.Effect != cardEffectTurn && c.Effect != cardEffectJump {
+			return fmt.Errorf(tr("牌%s的效果%s不存在"), c.Text, c.Effect)
+		}
+	}
+	return nil
+}
+
+// deckSummary 按牌面统计数量，例如"5×1 7×2"
+func deckSummary(cards []*card) string {
+	return strings.Join(deckCounts(cards), " ")
+}
+
+func deckCounts(cards []*card) []string {
+	var texts []string
+	count := make(map[string]int)
+	for _, c := range cards {
+		if count[c.Text] == 0 {
+			texts = append(texts, c.Text)
+		}
+		count[c.Text]++
+	}
+	var s []string
+	for _, t := range texts {
+		s = append(s, fmt.Sprintf("%s×%d", tr(t), count[t]))
+	}
+	return s
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func testCards(steps ...int) []*card {
+	var cards []*card
+	for _, s := range steps {
+		cards = append(cards, &card{Text: strconv.Itoa(s), Step: s, Kills: 99})
+	}
+	return cards
+}
+
+func cardSteps(cards []*card) []int {
+	steps := make([]int, 0, len(cards))
+	for _, c := range cards {
+		steps = append(steps, c.Step)
+	}
+	return steps
+}
+
+func sortedSteps(cards ...[]*card) []int {
+	var steps []int
+	for _, c := range cards {
+		steps = append(steps, cardSteps(c)...)
+	}
+	sort.Ints(steps)
+	return steps
+}
+
+// testMonster 返回一个用固定随机数种子的棋盘上的怪物
+func testMonster(seed int64, r rules) (*board, *monster) {
+	b := newEmptyBoard(newGeometry(15, 10, 3))
+	b.random = rand.New(rand.NewSource(seed))
+	b.rules = &r
+	return b, b.monsters[0]
+}
+
+func TestMonsterDraw(t *testing.T) {
+	tests := []struct {
+		name     string
+		draw     firstDrawRule
+		exclude  int
+		round    int
+		deck     []int
+		wantStep int
+		// wantDeck 为nil时只检查抽到的牌和剩下的牌的数量
+		wantDeck []int
+	}{
+		{"第一轮压到底下", firstDrawBury, 8, 0, []int{20, 10, 5, 7, 8, 7}, 5, []int{7, 7, 20, 10, 8}},
+		{"第一轮重新洗牌", firstDrawReshuffle, 20, 0, []int{20, 20, 5, 7, 8}, 0, nil},
+		{"最上面的牌可以用时不洗牌", firstDrawReshuffle, 20, 0, []int{5, 20, 20}, 5, []int{20, 20}},
+		{"第二轮不排除", firstDrawReshuffle, 20, 1, []int{20, 5}, 20, []int{5}},
+		{"不排除任何牌", firstDrawBury, 0, 0, []int{20, 5}, 20, []int{5}},
+		{"所有的牌都被排除时照常抽", firstDrawReshuffle, 5, 0, []int{7, 8}, 7, []int{8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 20; seed++ {
+				r := *rulesPresets()[0]
+				r.FirstDraw, r.FirstRoundExcludeStep = tt.draw, tt.exclude
+				b, m := testMonster(seed, r)
+				m.round = tt.round
+				m.deck = testCards(tt.deck...)
+				c := m.draw(b)
+				if tt.wantStep > 0 && c.Step != tt.wantStep {
+					t.Fatalf("种子%d抽到了%d", seed, c.Step)
+				}
+				if tt.wantStep == 0 && c.Step >= tt.exclude {
+					t.Fatalf("种子%d第一轮抽到了%d", seed, c.Step)
+				}
+				if len(m.discard) != 1 || m.discard[0] != c {
+					t.Fatalf("弃牌堆是%v", cardSteps(m.discard))
+				}
+				if tt.wantDeck != nil && !reflect.DeepEqual(cardSteps(m.deck), tt.wantDeck) {
+					t.Fatalf("牌堆是%v，应该是%v", cardSteps(m.deck), tt.wantDeck)
+				}
+				if got, want := sortedSteps(m.deck, m.discard), sortedSteps(testCards(tt.deck...)); !reflect.DeepEqual(got, want) {
+					t.Fatalf("牌变成了%v，应该是%v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMonsterCheckExhausted(t *testing.T) {
+	tests := []struct {
+		name        string
+		reshuffleAt int
+		cycle       int
+		deck        int
+		discard     int
+		wantCycle   int
+		wantDeck    int
+		wantDiscard int
+	}{
+		{"没到重新洗牌的张数", 1, 0, 2, 3, 0, 2, 3},
+		{"剩下ReshuffleAt张时重新洗牌", 1, 0, 1, 4, 1, 5, 0},
+		{"牌用完时重新洗牌", 0, 0, 0, 5, 1, 5, 0},
+		{"ReshuffleAt为0时还有牌不洗", 0, 0, 1, 4, 0, 1, 4},
+		{"第二次用完时不再洗牌", 1, 1, 1, 4, 2, 1, 4},
+		{"游戏结束后不变", 1, 2, 0, 5, 2, 0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := *rulesPresets()[0]
+			r.ReshuffleAt = tt.reshuffleAt
+			b, m := testMonster(1, r)
+			steps := []int{5, 7, 7, 8, 8, 10, 20, 20}[:tt.deck+tt.discard]
+			all := testCards(steps...)
+			m.deck, m.discard, m.cycle = all[:tt.deck], all[tt.deck:], tt.cycle
+			m.checkExhausted(b)
+			if m.cycle != tt.wantCycle || len(m.deck) != tt.wantDeck || len(m.discard) != tt.wantDiscard {
+				t.Fatalf("第%d次用完，牌堆%d张，弃牌堆%d张", m.cycle, len(m.deck), len(m.discard))
+			}
+			if got, want := sortedSteps(m.deck, m.discard), sortedSteps(all); !reflect.DeepEqual(got, want) {
+				t.Fatalf("牌变成了%v，应该是%v", got, want)
+			}
+		})
+	}
+}
+
+// TestMonsterDeckCycles 用默认的牌抽完两遍，第一遍用完后重新洗牌，第二遍用完后游戏结束
+func TestMonsterDeckCycles(t *testing.T) {
+	r := *rulesPresets()[0]
+	r.ReshuffleAt = 2
+	b, m := testMonster(7, r)
+	m.round = 1
+	m.deck = newDeck(b.random, defaultDeck())
+	draws := 0
+	for m.cycle < 2 {
+		m.draw(b)
+		m.checkExhausted(b)
+		draws++
+		if draws > 100 {
+			t.Fatal("牌堆一直没有用完")
+		}
+	}
+	// 第一遍抽6张后剩2张，和弃牌一起洗回8张，第二遍再抽6张
+	if draws != 12 || len(m.deck) != 2 || len(m.discard) != 6 {
+		t.Fatalf("抽了%d张，牌堆%d张，弃牌堆%d张", draws, len(m.deck), len(m.discard))
+	}
+}
+
+func TestPileJSON(t *testing.T) {
+	p := &pile{deck: testCards(5, 7), discard: testCards(20), cycle: 1}
+	p.discard[0].Effect = cardEffectJump
+	buf, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got pile
+	if err = json.Unmarshal(buf, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, p) {
+		t.Fatalf("%s读回来不一样", buf)
+	}
+	if err = json.Unmarshal([]byte(`{"deck":[],"discard":[],"cycle":3}`), &got); err == nil {
+		t.Fatal("cycle为3时应该返回错误")
+	}
+}
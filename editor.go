@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"os"
+)
+
+type editorTool int
+
+const (
+	editorToolStone editorTool = iota
+	editorToolSlipFloor
+	editorToolTeleporter
+	editorToolMonster
+)
+
+func (t editorTool) String() string {
+	switch t {
+	case editorToolStone:
+		return tr("石头")
+	case editorToolSlipFloor:
+		return tr("血池")
+	case editorToolTeleporter:
+		return tr("传送阵")
+	case editorToolMonster:
+		return tr("怪物")
+	}
+	return ""
+}
+
+type editor struct {
+	board *board
+	file  string
+	tool  editorTool
+	// monster 当前选中的怪物，R键旋转它，怪物工具移动它
+	monster int
+	message string
+}
+
+func newEditor(file string, geo *geometry) *editor {
+	e := &editor{board: newEmptyBoard(geo), file: file}
+	l, err := loadLayout(file, geo)
+	if err == nil {
+		e.board = newEmptyBoard(&l.Geometry)
+		_ = e.board.applyLayout(l)
+		e.message = tr("已读取") + file
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logger.WithError(err).Warn("load layout failed")
+		e.message = tr("读取失败：") + err.Error()
+	}
+	ebiten.SetWindowTitle(tr("编辑器 - ") + file)
+	return e
+}
+
+func (e *editor) Update() error {
+	b := e.board
+	b.updateAnim()
+	for _, m := range b.monsters {
+		m.animate(m.pos)
+	}
+	m := b.monsters[e.monster]
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyDigit1):
+		e.tool = editorToolStone
+	case inpututil.IsKeyJustPressed(ebiten.KeyDigit2):
+		e.tool = editorToolSlipFloor
+	case inpututil.IsKeyJustPressed(ebiten.KeyDigit3):
+		e.tool = editorToolTeleporter
+	case inpututil.IsKeyJustPressed(ebiten.KeyDigit4):
+		e.tool = editorToolMonster
+	case inpututil.IsKeyJustPressed(ebiten.KeyR):
+		m.faceTo = dir{m.faceTo.y, -m.faceTo.x}
+	case inpututil.IsKeyJustPressed(ebiten.KeyN):
+		e.addMonster()
+	case inpututil.IsKeyJustPressed(ebiten.KeyV):
+		if err := b.toLayout().validate(); err != nil {
+			e.message = tr("校验失败：") + err.Error()
+		} else {
+			e.message = tr("校验通过")
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyS):
+		l := b.toLayout()
+		if err := l.validate(); err != nil {
+			e.message = tr("校验失败，未保存：") + err.Error()
+		} else if err = l.save(e.file); err != nil {
+			logger.WithError(err).Error("save layout failed")
+			e.message = tr("保存失败：") + err.Error()
+		} else {
+			e.message = tr("已保存到") + e.file
+		}
+	}
+	pos, ok := b.geo.cellAt(ebiten.CursorPosition())
+	if !ok {
+		return nil
+	}
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) {
+		b.items[pos.y][pos.x] = nil
+		b.floorShape[pos.y][pos.x] = floorShapeTypeEmpty
+		if other := b.monsterAt(pos); other != nil && other.index > 0 {
+			e.removeMonster(other)
+		}
+	} else if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		switch e.tool {
+		case editorToolStone:
+			if b.monsterAt(pos) == nil && b.floorShape[pos.y][pos.x] < floorShapeTypeTransferUp {
+				b.items[pos.y][pos.x] = &stoneRegular{pos: pos}
+			}
+		case editorToolSlipFloor:
+			b.floorShape[pos.y][pos.x] = floorShapeTypeSlipFloor
+		case editorToolTeleporter:
+			b.items[pos.y][pos.x] = nil
+			b.floorShape[pos.y][pos.x] = floorShapeTypeTransferUp
+		case editorToolMonster:
+			if other := b.monsterAt(pos); other != nil {
+				e.monster = other.index
+			} else if b.items[pos.y][pos.x] == nil {
+				m.pos = pos
+			}
+		}
+	}
+	return nil
+}
+
+// addMonster 在鼠标所在的空地上加一个怪物并选中它
+func (e *editor) addMonster() {
+	b := e.board
+	pos, ok := b.geo.cellAt(ebiten.CursorPosition())
+	switch {
+	case len(b.monsters) >= maxMonsters:
+		e.message = trf("最多只能有%d个怪物", maxMonsters)
+	case !ok || b.items[pos.y][pos.x] != nil || b.monsterAt(pos) != nil:
+		e.message = tr("把鼠标移到空地上再按N键")
+	default:
+		m := newMonster(b.geo)
+		m.index, m.pos, m.pile = len(b.monsters), pos, b.monsters[0].pile
+		m.teleport(pos)
+		b.monsters = append(b.monsters, m)
+		e.monster = m.index
+	}
+}
+
+// removeMonster 删掉一个怪物，第一个怪物不能删
+func (e *editor) removeMonster(m *monster) {
+	b := e.board
+	b.monsters = append(b.monsters[:m.index], b.monsters[m.index+1:]...)
+	for i, other := range b.monsters {
+		other.index = i
+	}
+	e.monster = 0
+}
+
+func (e *editor) Draw(screen *ebiten.Image) {
+	e.board.drawBoard(screen)
+	g := e.board.geo
+	if pos, ok := g.cellAt(ebiten.CursorPosition()); ok {
+		opt := &ebiten.DrawImageOptions{}
+		opt.GeoM.Scale(float64(g.gridLen-1), float64(g.gridLen-1))
+		opt.GeoM.Translate(g.cellX(float64(pos.x)), g.cellY(float64(pos.y)))
+		opt.ColorM.ScaleWithColor(theView.theme.Highlight)
+		opt.ColorM.Scale(1, 1, 1, 0.2)
+		screen.DrawImage(emptyImage, opt)
+	}
+	t := theView.theme
+	if m := e.board.monsters[e.monster]; len(e.board.monsters) > 1 {
+		drawFrame(screen, g.cellX(float64(m.pos.x)), g.cellY(float64(m.pos.y)), float64(g.gridLen), float64(g.gridLen), ui(3), t.Highlight)
+	}
+	text.Draw(screen, tr("当前工具：")+e.tool.String()+tr("（1石头 2血池 3传送阵 4怪物 N加怪物 R转向 V校验 S保存）"), fontAlpha, uiInt(10), uiInt(30), t.Text)
+	text.Draw(screen, e.message, fontAlpha, uiInt(10), uiInt(60), t.Text)
+}
+
+func (e *editor) Layout(outsideWidth, outsideHeight int) (int, int) {
+	w, h := layoutView(outsideWidth, outsideHeight)
+	e.board.geo.init()
+	return w, h
+}
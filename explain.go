@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+var decisionDirs = [4]dir{left, up, right, down}
+
+type decisionReason int
+
+const (
+	reasonNothingSeen decisionReason = iota
+	reasonTie
+	reasonAhead
+	reasonTurn
+	reasonHunt
+	reasonRandom
+	reasonAdversary
+)
+
+// sight 怪物朝一个方向看到的东西
+type sight struct {
+	distance int
+	seen     []*playerItem
+	// behind 怪物不会看身后
+	behind bool
+	// blocked 视线被石头或别的怪物挡住了，hidden是石头后面的棋子
+	blocked bool
+	hidden  []*playerItem
+}
+
+// decision 记录怪物每次选择方向时的依据，用于解释怪物为什么这样走
+type decision struct {
+	pos      point
+	from, to dir
+	sights   [4]sight
+	reason   decisionReason
+	// target 怪物针对的玩家
+	target string
+}
+
+func (b *board) piecesOn(pos point) []*playerItem {
+	var items []*playerItem
+	for _, player := range b.player {
+		for _, item := range player.items {
+			if item.pos == pos {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// piecesInLine 从pos开始沿着d方向找到的第一批棋子
+func (b *board) piecesInLine(pos point, d dir) []*playerItem {
+	for ; !b.geo.outOfRange(pos); pos = (point{pos.x + d.x, pos.y + d.y}) {
+		if items := b.piecesOn(pos); len(items) > 0 {
+			return items
+		}
+	}
+	return nil
+}
+
+func dirName(d dir) string {
+	switch d {
+	case up:
+		return tr("上")
+	case left:
+		return tr("左")
+	case down:
+		return tr("下")
+	case right:
+		return tr("右")
+	}
+	return d.String()
+}
+
+func pieceIDs(items []*playerItem) string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.id)
+	}
+	return strings.Join(ids, ",")
+}
+
+func (v sight) String() string {
+	switch {
+	case v.behind:
+		return tr("身后")
+	case v.blocked && len(v.hidden) > 0:
+		return trf("被石头挡住（后面有%s）", pieceIDs(v.hidden))
+	case v.blocked:
+		return tr("被石头挡住")
+	case v.distance >= 99:
+		return tr("看不到")
+	}
+	return trf("%d格（%s）", v.distance, pieceIDs(v.seen))
+}
+
+func (d *decision) reasonText() string {
+	switch d.reason {
+	case reasonNothingSeen:
+		return trf("哪个方向都看不到棋子，继续向%s", dirName(d.to))
+	case reasonTie:
+		return trf("有几个方向的棋子一样近，继续向%s", dirName(d.to))
+	case reasonAhead:
+		return trf("最近的棋子就在前面，继续向%s", dirName(d.to))
+	case reasonHunt:
+		return trf("追领先的%s，最近的棋子在%s边", d.target, dirName(d.to))
+	case reasonRandom:
+		return trf("随便选了%s边", dirName(d.to))
+	case reasonAdversary:
+		return trf("向%s走能吃掉领先的%s最多的棋子", dirName(d.to), d.target)
+	}
+	return trf("最近的棋子在%s边，转向那边", dirName(d.to))
+}
+
+func (d *decision) lines(g *geometry) []string {
+	lines := []string{trf("怪物在%s，原来朝%s", g.colLabel(d.pos.x)+g.rowLabel(d.pos.y), dirName(d.from))}
+	for i, dd := range decisionDirs {
+		lines = append(lines, dirName(dd)+"："+d.sights[i].String())
+	}
+	return append(lines, d.reasonText())
+}
+
+func (d *decision) log() {
+	fields := logrus.Fields{"pos": d.pos, "from": d.from, "to": d.to, "reason": d.reasonText()}
+	for i, dd := range decisionDirs {
+		v := d.sights[i]
+		fields[dd.String()] = fmt.Sprintf("distance=%d seen=%s blocked=%t hidden=%s", v.distance, pieceIDs(v.seen), v.blocked, pieceIDs(v.hidden))
+	}
+	logger.WithFields(fields).Info("monster decision")
+}
+
+// drawExplain 在怪物旁边显示它最后一次选择方向的依据
+func (b *board) drawExplain(screen *ebiten.Image) {
+	m := b.activeMonster()
+	d := m.decision
+	if d == nil {
+		return
+	}
+	g := b.geo
+	x, y := m.drawn()
+	var lines []textPart
+	for _, line := range d.lines(g) {
+		lines = append(lines, textPart{line, theView.theme.Text})
+	}
+	drawTextBox(screen, int(g.cellX(x))+g.gridLen, int(g.cellY(y)), lines)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+)
+
+const (
+	tileSize     = 60
+	screenWidth  = 1280
+	screenHeight = 768
+	panelWidth   = 256
+)
+
+type geometry struct {
+	Width    int   `json:"width"`
+	Height   int   `json:"height"`
+	Cut      int   `json:"cut"`
+	Entrance point `json:"entrance"`
+	Exit     point `json:"exit"`
+
+	edgeX, edgeY, gridLen int
+}
+
+func newGeometry(width, height, cut int) *geometry {
+	g := &geometry{
+		Width:    width,
+		Height:   height,
+		Cut:      cut,
+		Entrance: point{0, 0},
+		Exit:     point{width - 1, height - 1},
+	}
+	g.init()
+	return g
+}
+
+func (g *geometry) init() {
+	w, h := theView.width, theView.height
+	if w == 0 || h == 0 {
+		w, h = screenWidth, screenHeight
+	}
+	g.edgeX, g.edgeY = uiInt(50), uiInt(100)
+	g.gridLen = uiInt(tileSize)
+	if l := (w - uiInt(panelWidth) - 2*g.edgeX) / g.Width; l < g.gridLen {
+		g.gridLen = l
+	}
+	if l := (h - g.edgeY - uiInt(60+trayHeight)) / g.Height; l < g.gridLen {
+		g.gridLen = l
+	}
+}
+
+func (g *geometry) validate() error {
+	if g.Width < 4 || g.Height < 4 || g.Width+g.Height > 40 {
+		return errors.New(tr("棋盘大小不合法"))
+	}
+	if g.Cut < 0 || g.Cut >= g.Width || g.Cut >= g.Height {
+		return errors.New(tr("切角大小不合法"))
+	}
+	if g.outOfRange(g.Entrance) || g.outOfRange(g.Exit) || g.Entrance == g.Exit {
+		return errors.New(tr("入口或出口不合法"))
+	}
+	if !g.outOfRange(g.startPos()) || !g.outOfRange(g.deadPos()) {
+		return errors.New(tr("入口必须在棋盘边缘"))
+	}
+	d := g.outside(g.Exit)
+	if !g.outOfRange(point{g.Exit.x + d.x, g.Exit.y + d.y}) {
+		return errors.New(tr("出口必须在棋盘边缘"))
+	}
+	return nil
+}
+
+func (g *geometry) outOfRange(p point) bool {
+	return p.x < 0 || p.x >= g.Width || p.y < 0 || p.y >= g.Height ||
+		g.Cut > 0 && (p.x-p.y >= g.Width-g.Cut || p.y-p.x >= g.Height-g.Cut)
+}
+
+func (g *geometry) outside(p point) dir {
+	for _, d := range []dir{up, left, down, right} {
+		if g.outOfRange(point{p.x + d.x, p.y + d.y}) {
+			return d
+		}
+	}
+	return up
+}
+
+func (g *geometry) startPos() point {
+	d := g.outside(g.Entrance)
+	return point{g.Entrance.x + d.x, g.Entrance.y + d.y}
+}
+
+func (g *geometry) deadPos() point {
+	d := g.outside(g.Entrance)
+	return point{g.Entrance.x + 2*d.x, g.Entrance.y + 2*d.y}
+}
+
+func (g *geometry) finishPos() point {
+	return point{g.Width, g.Height}
+}
+
+func (g *geometry) isExitGate(p point) bool {
+	if !g.outOfRange(p) {
+		return false
+	}
+	for _, d := range []dir{up, left, down, right} {
+		if p.x+d.x == g.Exit.x && p.y+d.y == g.Exit.y {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *geometry) nearEntrance(p point) bool {
+	return abs(p.x-g.Entrance.x) < 3 && abs(p.y-g.Entrance.y) < 3
+}
+
+func (g *geometry) mirror(p point) point {
+	return point{g.Width - 1 - p.x, g.Height - 1 - p.y}
+}
+
+func (g *geometry) scale() float64 {
+	return float64(g.gridLen) / tileSize
+}
+
+func (g *geometry) cellX(x float64) float64 {
+	return float64(g.edgeX+1) + x*float64(g.gridLen)
+}
+
+func (g *geometry) cellY(y float64) float64 {
+	return float64(g.edgeY+1) + y*float64(g.gridLen)
+}
+
+func (g *geometry) cellAt(x, y int) (point, bool) {
+	if x <= g.edgeX || y <= g.edgeY {
+		return point{}, false
+	}
+	p := point{(x - g.edgeX - 1) / g.gridLen, (y - g.edgeY - 1) / g.gridLen}
+	return p, !g.outOfRange(p)
+}
+
+func (g *geometry) colLabel(i int) string {
+	if i >= 26 {
+		return strconv.Itoa(i + 1)
+	}
+	return string(rune('A' + i))
+}
+
+func (g *geometry) rowLabel(j int) string {
+	if g.Width+g.Height > 26 {
+		return strconv.Itoa(j + 1)
+	}
+	return string(rune('Z' - j))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+type textPart struct {
+	s string
+	c color.Color
+}
+
+// drawTexts 在同一行依次画出不同颜色的文字
+func drawTexts(screen *ebiten.Image, face font.Face, x, y int, parts ...textPart) {
+	for _, part := range parts {
+		text.Draw(screen, part.s, face, x, y, part.c)
+		x += font.MeasureString(face, part.s).Ceil()
+	}
+}
+
+func (b *board) drawHUD(screen *ebiten.Image) {
+	t := theView.theme
+	p := b.player[b.curPlayer]
+	var steps []string
+	for _, item := range p.canMoveItems(b) {
+		steps = append(steps, item.id+":"+strconv.Itoa(item.step))
+	}
+	phase := tr("第一阶段")
+	if b.cycle() > 0 {
+		phase = tr("第二阶段")
+	}
+	if b.gameOver() && !b.monsterBusy() && b.score != nil {
+		drawTexts(screen, fontAlpha, uiInt(20), uiInt(32), textPart{trf("第%d轮　游戏结束　得分：%d", b.bigTurn, b.score.Score), t.Text})
+	} else if b.gameOver() && b.puzzle != nil {
+		drawTexts(screen, fontAlpha, uiInt(20), uiInt(32), textPart{b.modeLabel() + tr("残局结束"), t.Text})
+	} else if b.gameOver() && !b.monsterBusy() {
+		parts := []textPart{{trf("第%d轮　游戏结束　获胜：", b.bigTurn), t.Text}}
+		for _, w := range b.winners() {
+			parts = append(parts, textPart{w.name() + " ", w.color})
+		}
+		drawTexts(screen, fontAlpha, uiInt(20), uiInt(32), parts...)
+	} else {
+		drawTexts(screen, fontAlpha, uiInt(20), uiInt(32),
+			textPart{b.modeLabel() + trf("第%d轮　", b.bigTurn+1) + phase + tr("　轮到"), t.Text},
+			textPart{p.name(), p.color},
+			textPart{tr("　能移动的棋子：") + strings.Join(steps, " "), t.Text},
+		)
+	}
+	text.Draw(screen, b.hint(), fontSmall, uiInt(20), uiInt(62), t.TextDim)
+
+	y := theView.height - uiInt(30*len(b.player)-10)
+	for _, p := range b.player {
+		finished, dead := p.countFinished(b)
+		drawTexts(screen, fontSmall, theView.width-uiInt(panelWidth-20), y,
+			textPart{p.name(), p.color},
+			textPart{trf("　逃出%d　死亡%d　剩余%d", finished, dead, len(p.items)-finished-dead), t.Text},
+		)
+		y += uiInt(30)
+	}
+	if b.mode != modeNormal {
+		p := b.player[0]
+		finished, _ := p.countFinished(b)
+		text.Draw(screen, trf("当前得分：%d　被吃%d次", scoreOf(finished, b.bigTurn, p.lost), p.lost), fontSmall,
+			theView.width-uiInt(panelWidth-20), theView.height-uiInt(30*len(b.player)+20), t.Text)
+	}
+	if b.score != nil {
+		b.drawHighScores(screen)
+	}
+}
+
+// drawHighScores 单人模式游戏结束后显示排行榜，这一局的成绩高亮
+func (b *board) drawHighScores(screen *ebiten.Image) {
+	t := theView.theme
+	title := trf("单人挑战排行榜（%s，%s怪物×%d）", tr(b.score.Rules), tr(b.score.Brain), b.score.Monsters)
+	if b.daily != "" {
+		title = trf("%s每日挑战排行榜", b.daily)
+	}
+	lines := []textPart{{title, t.Text}}
+	for i, s := range b.scoreTable {
+		c := t.TextDim
+		if i+1 == b.scoreRank {
+			c = t.Highlight
+		}
+		lines = append(lines, textPart{trf("%d. %d分　逃出%d　%d轮　被吃%d次", i+1, s.Score, s.Escaped, s.Rounds, s.Lost), c})
+	}
+	if b.scoreRank == 0 {
+		lines = append(lines, textPart{trf("这一局%d分，没有进入排行榜", b.score.Score), t.Text})
+	}
+	drawTextBox(screen, uiInt(60), uiInt(120), lines)
+}
+
+// modeLabel 残局和教程在状态栏前面显示模式，窗口标题只显示游戏名
+func (b *board) modeLabel() string {
+	switch {
+	case b.tutorial != nil:
+		return tr("教程") + "　"
+	case b.puzzle != nil:
+		return tr("残局：") + b.puzzle.Name + "　"
+	}
+	return ""
+}
+
+func (b *board) hint() string {
+	switch {
+	case b.monsterBusy():
+		return tr("怪物正在移动……")
+	case b.puzzle != nil && !b.puzzleDone && b.pickedPlayerItem == nil:
+		return tr("残局目标：") + strings.Join(b.puzzle.Goal.goals(), "，") + tr("。按数字键或Tab键选择要移动的棋子")
+	case b.puzzle != nil && b.gameOver():
+		return tr("按Enter键重来")
+	case b.gameOver() && b.mode != modeNormal:
+		return tr("逃出的棋子越多、用的轮数越少、被吃的次数越少，得分越高")
+	case b.gameOver():
+		return tr("逃出棋子最多的玩家获胜")
+	case b.pickedPlayerItem == nil && b.cycle() > 0 && b.rules.PermanentDeath:
+		return tr("第二阶段：被怪物吃掉的棋子不能再回到起点。按数字键或Tab键选择要移动的棋子")
+	case b.pickedPlayerItem == nil:
+		return tr("按数字键或Tab键选择要移动的棋子")
+	}
+	left := b.pickedPlayerItem.step - b.alreadyMoveCount
+	if left > 0 {
+		return trf("已选择棋子%s，还能走%d步。按方向键移动，Enter键确定，Esc键撤销", b.pickedPlayerItem.id, left)
+	}
+	if !b.pickedPlayerItem.checkLegal(b) {
+		return tr("不能停在这里，按Esc键撤销")
+	}
+	return tr("步数已用完，按Enter键确定，Esc键撤销")
+}
+
+// status 棋子的状态，例如"A1 3→4"表示现在走3步，下一轮走4步
+func (p *playerItem) status(b *board) string {
+	switch {
+	case p.isFinished(b):
+		return p.id + " " + tr("逃出")
+	case p.isDead(b):
+		return p.id + " " + tr("死亡")
+	}
+	s := fmt.Sprintf("%s %d→%d", p.id, p.step, p.next(b.rules))
+	if p.alreadyMove {
+		s += tr("（已走）")
+	}
+	return s
+}
+
+// drawPieceInfo 列出每个玩家所有棋子现在和下一轮的步数，以及这一轮是否已经走过
+func (b *board) drawPieceInfo(screen *ebiten.Image) {
+	t := theView.theme
+	x, y := ui(40), ui(90)
+	w, h := float64(theView.width)-ui(panelWidth)-2*x, ui(float64(50+34*len(b.player)))
+	drawRect(screen, x, y, w, h, t.Background)
+	drawFrame(screen, x, y, w, h, ui(2), t.Grid)
+	text.Draw(screen, tr("棋子一览（按I键关闭）"), fontAlpha, int(x+ui(16)), int(y+ui(32)), t.Text)
+	for i, p := range b.player {
+		parts := []textPart{{p.name() + "　", p.color}}
+		for _, item := range p.items {
+			c := p.color
+			if !item.canMove(b) {
+				c = t.TextDim
+			}
+			parts = append(parts, textPart{item.status(b) + "　", c})
+		}
+		drawTexts(screen, fontSmall, int(x+ui(16)), int(y+ui(float64(70+34*i))), parts...)
+	}
+}
+
+// drawTooltip 鼠标停在棋子上时显示这个位置上所有棋子的状态
+func (b *board) drawTooltip(screen *ebiten.Image) {
+	mx, my := ebiten.CursorPosition()
+	var lines []textPart
+	for _, item := range b.piecesAt(mx, my) {
+		lines = append(lines, textPart{item.status(b), item.color})
+	}
+	drawTextBox(screen, mx+uiInt(16), my+uiInt(16), lines)
+}
+
+// drawTextBox 在(x, y)处画一个带边框的文字框，超出屏幕时往回移
+func drawTextBox(screen *ebiten.Image, x, y int, lines []textPart) {
+	if len(lines) == 0 {
+		return
+	}
+	t := theView.theme
+	w := 0
+	for _, line := range lines {
+		if lw := font.MeasureString(fontSmall, line.s).Ceil(); lw > w {
+			w = lw
+		}
+	}
+	lineHeight := uiInt(24)
+	boxW, boxH := w+uiInt(16), lineHeight*len(lines)+uiInt(12)
+	if x+boxW > theView.width {
+		x = theView.width - boxW
+	}
+	if y+boxH > theView.height {
+		y = theView.height - boxH
+	}
+	drawRect(screen, float64(x), float64(y), float64(boxW), float64(boxH), t.Background)
+	drawFrame(screen, float64(x), float64(y), float64(boxW), float64(boxH), ui(1), t.Grid)
+	for i, line := range lines {
+		text.Draw(screen, line.s, fontSmall, x+uiInt(8), y+lineHeight*(i+1), line.c)
+	}
+}
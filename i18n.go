@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"image"
+)
+
+// locale 以中文原文作为消息的key，messages中找不到的消息直接显示原文
+type locale struct {
+	Code     string
+	Name     string
+	messages map[string]string
+	// latinFirst 为true时优先使用拉丁字体，缺少的字形再用中文字体补上
+	latinFirst bool
+}
+
+var locales = []*locale{
+	{Code: "zh-CN", Name: "简体中文"},
+	{Code: "en", Name: "English", latinFirst: true, messages: map[string]string{
+		// 玩家
+		"红方": "Red",
+		"绿方": "Green",
+		"黄方": "Yellow",
+		"蓝方": "Blue",
+		"紫方": "Purple",
+		"橙方": "Orange",
+
+		// HUD
+		"第%d轮　":         "Round %d  ",
+		"　轮到":           ", turn: ",
+		"第一阶段":          "phase 1",
+		"第二阶段":          "phase 2",
+		"第%d轮　游戏结束　获胜：": "Game over after round %d. Winner: ",
+		"逃出棋子最多的玩家获胜":   "The player with the most escaped pieces wins",
+		"第二阶段：被怪物吃掉的棋子不能再回到起点。按数字键或Tab键选择要移动的棋子": "Phase 2: eaten pieces don't come back. Press a number key or Tab to pick a piece",
+		"　能移动的棋子：":          "  Movable pieces: ",
+		"　逃出%d　死亡%d　剩余%d":   "  Out %d  Dead %d  Left %d",
+		"怪物正在移动……":          "The monster is moving...",
+		"按数字键或Tab键选择要移动的棋子": "Press a number key or Tab to pick a piece",
+		"已选择棋子%s，还能走%d步。按方向键移动，Enter键确定，Esc键撤销": "Piece %s picked, %d steps left. Arrows to move, Enter to confirm, Esc to undo",
+		"不能停在这里，按Esc键撤销":                        "You can't stop here, press Esc to undo",
+		"步数已用完，按Enter键确定，Esc键撤销":                "No steps left, press Enter to confirm or Esc to undo",
+
+		"逃出":          "out",
+		"死亡":          "dead",
+		"（已走）":        " (moved)",
+		"棋子一览（按I键关闭）": "All pieces (press I to close)",
+
+		"起点": "Start",
+		"终点": "Finish",
+		"墓地": "Graveyard",
+
+		"上":            "up",
+		"左":            "left",
+		"下":            "down",
+		"右":            "right",
+		"身后":           "behind",
+		"被石头挡住（后面有%s）": "blocked by a stone (%s behind it)",
+		"被石头挡住":        "blocked by a stone",
+		"看不到":          "nothing",
+		"%d格（%s）":      "%d (%s)",
+		"哪个方向都看不到棋子，继续向%s":  "Sees no piece, keeps going %s",
+		"有几个方向的棋子一样近，继续向%s": "Pieces equally near in several directions, keeps going %s",
+		"最近的棋子就在前面，继续向%s":   "Nearest piece is ahead, keeps going %s",
+		"最近的棋子在%s边，转向那边":    "Nearest piece is to the %s, turns that way",
+		"怪物在%s，原来朝%s":       "Monster at %s, facing %s",
+
+		"怪物AI：": "Monster AI: ",
+		"怪物数量：": "Monsters: ",
+		"模式：":   "Mode: ",
+		"多人":    "Multiplayer",
+		"单人挑战":  "Solo challenge",
+		"每日挑战":  "Daily challenge",
+		"每日挑战：默认棋盘和规则、一个经典怪物":  "Daily: default board and rules, one classic monster",
+		"得分：逃出%d分，每轮%d分，被吃%d分": "Score: %d per escape, %d per round, %d per loss",
+		"最高分：":            "Best: ",
+		"无":               "none",
+		"第%d轮　游戏结束　得分：%d": "Round %d  Game over  Score: %d",
+		"当前得分：%d　被吃%d次":   "Score: %d  Lost %d times",
+		"残局没有名字":          "The puzzle has no name",
+		"残局的玩家数不合法":       "Invalid number of players in the puzzle",
+		"残局的怪物牌不合法":       "Invalid monster card in the puzzle",
+		"每个玩家要有1-6个棋子":    "Each player needs 1-6 pieces",
+		"棋子步数%d不合法":       "Invalid piece step %d",
+		"棋子位置%s不合法":       "Invalid piece position %s",
+		"棋子不能放在%v":        "A piece cannot be placed at %v",
+		"%v有两个棋子":         "There are two pieces at %v",
+		"让%s逃出":           "Escape %s",
+		"至少逃出%d个棋子":       "Escape at least %d pieces",
+		"不能有棋子被吃掉":        "No piece may be eaten",
+		"%s没有逃出":          "%s did not escape",
+		"只逃出了%d个棋子":       "Only %d pieces escaped",
+		"有%d个棋子被吃掉了":      "%d pieces were eaten",
+		"残局：":             "Puzzle: ",
+		"目标：":             "Goal: ",
+		"成功！":             "Solved!",
+		"失败：":             "Failed: ",
+		"最好的解法：":          "Best solution: ",
+		"按Enter键重来":       "Press Enter to retry",
+		"残局结束":            "Puzzle over",
+		"残局目标：":           "Puzzle goal: ",
+		"。按数字键或Tab键选择要移动的棋子": ". Press a digit or Tab to pick a piece",
+		"教程":       "Tutorial",
+		"教程 %d/%d": "Tutorial %d/%d",
+		"教程：一步一步学会移动棋子、推石头、": "Tutorial: learn step by step how to move pieces, push stones,",
+		"血池和怪物转向的规则":         "slide on blood pools and how the monster turns",
+		"欢迎来到教程！":            "Welcome to the tutorial!",
+		"你要让棋子从左上角的入口走到右下角的出口，同时躲开怪物。": "Get your pieces from the entrance at the top left to the exit at the bottom right while avoiding the monster.",
+		"按Enter键继续": "Press Enter to continue",
+		"每个棋子一次要走的步数就是它上面的数字。": "Each piece moves as many steps as the number on it.",
+		"按3键选择A1": "Press 3 to pick A1",
+		"棋子可以推动前面的石头，石头后面有东西时推不动。":               "A piece can push the stone in front of it, unless something is behind the stone.",
+		"按方向键向右走三步，把石头推过去，停在高亮的格子上，然后按Enter键确定。": "Press Right three times to push the stone, stop on the highlighted cell and press Enter.",
+		"走错了可以按Esc键撤销": "Press Esc to undo a mistake",
+		"红色的格子是血池，走上去会一直滑到血池外面，滑的时候不算步数。":    "Red cells are blood pools: you slide across them, and sliding costs no steps.",
+		"按1键选择A2，向右走一步，然后按Enter键确定":          "Press 1 to pick A2, move one step right and press Enter",
+		"从出口走出棋盘的棋子就逃出了。":                    "A piece that walks out through the exit has escaped.",
+		"按2键选择A3，先向下再向右走出出口，然后按Enter键确定":     "Press 2 to pick A3, go down and then right through the exit, and press Enter",
+		"所有棋子都走完后，怪物抽一张牌，按牌上的步数移动。":          "When every piece has moved, the monster draws a card and walks that many steps.",
+		"怪物每走一步都会转向看得到的最近的棋子，旁边的框解释了它为什么这样走": "After each step it turns towards the nearest piece it can see; the box next to it explains why",
+		"石头挡住了怪物看A1的视线，所以它一直向左走，":            "The stone hid A1 from the monster, so it kept walking left;",
+		"最后一步看到了下面的A2，转向了下面。被怪物撞到的棋子会被吃掉。":   "on its last step it saw A2 below and turned down. Pieces the monster walks into are eaten.",
+		"按Enter键结束教程":            "Press Enter to finish the tutorial",
+		"要停在高亮的格子上，按Esc键撤销再试一次":  "Stop on the highlighted cell; press Esc to undo and try again",
+		"棋盘是空的":                  "The board is empty",
+		"%s格式不对：%s":              "Malformed %s: %s",
+		"看不懂%q":                  "Cannot parse %q",
+		"第%d行有%d格，应该有%d格":        "Row %d has %d cells, expected %d",
+		"切角和第%d行第%d格不一致":         "Cut corner does not match row %d cell %d",
+		"棋盘上没有棋子":                "There are no pieces on the board",
+		"单人挑战排行榜（%s，%s怪物×%d）":    "Solo high scores (%s, %s monster ×%d)",
+		"%s每日挑战排行榜":              "Daily challenge %s high scores",
+		"%d. %d分　逃出%d　%d轮　被吃%d次": "%d. %d pts  escaped %d  %d rounds  lost %d",
+		"这一局%d分，没有进入排行榜":         "Scored %d, not on the table",
+		"逃出的棋子越多、用的轮数越少、被吃的次数越少，得分越高": "Escape more pieces in fewer rounds with fewer losses to score higher",
+		"多个怪物的牌堆：": "Monster decks: ",
+		"共用":       "Shared",
+		"各自":       "Separate",
+		"%d号怪物的牌":  "Monster %d card",
+		"猎手":       "Hunter",
+		"随机":       "Random",
+		"针对领先者":    "Adversarial",
+		"追领先的%s，最近的棋子在%s边":  "Hunting the leader %s, nearest piece is %s",
+		"随便选了%s边":           "Randomly picked %s",
+		"向%s走能吃掉领先的%s最多的棋子": "Going %s eats the most pieces of the leader %s",
+
+		// 怪物牌
+		"怪物牌":      "Monster card",
+		"不限":       "any",
+		"剩余步数：%d":  "Steps left: %d",
+		"还能吃掉：":    "Can still eat: ",
+		"牌堆剩余%d张":  "%d cards in deck",
+		"洗牌后已弃%d张": "%d discarded since shuffle",
+		"转身7":      "Turn 7",
+		"跳跃5":      "Jump 5",
+
+		// 设置界面
+		"人数：":           "Players: ",
+		"规则：":           "Rules: ",
+		"动画：":           "Animation: ",
+		"音效音量：%.0f%%":   "Sound volume: %.0f%%",
+		"音乐音量：%.0f%%":   "Music volume: %.0f%%",
+		"静音：":           "Mute: ",
+		"主题：":           "Theme: ",
+		"语言：":           "Language: ",
+		"色盲配色：":         "Colorblind palette: ",
+		"高对比度：":         "High contrast: ",
+		"开":             "on",
+		"关":             "off",
+		"基础":            "Basic",
+		"进阶":            "Advanced",
+		"儿童":            "Kids",
+		"经典":            "Classic",
+		"夜间":            "Night",
+		"永不":            "never",
+		"（放到牌底）":        " (put at the bottom)",
+		"（重新洗牌）":        " (reshuffle)",
+		"重新洗牌：牌堆剩%d张时":  "Reshuffle: at %d cards left",
+		"第二阶段（第二副牌）":    "in phase 2 (second deck)",
+		"无限制":           "no limit",
+		"不抽%d步及以上的牌":    "no cards of %d steps or more",
+		"棋子翻面：%d减去当前步数": "Piece flip: %d minus current steps",
+		"怪物第一轮：":        "Monster's first round: ",
+		"棋子永久死亡：":       "Permanent death: ",
+		"每人的棋子：":        "Pieces each: ",
+		"石头数量：%d":       "Stones: %d",
+		"怪物牌堆：":         "Monster deck: ",
+		"上下键选择，左右键修改，Enter键开始游戏": "Up/Down to select, Left/Right to change, Enter to start",
+
+		// 编辑器
+		"石头":        "Stone",
+		"血池":        "Blood pool",
+		"传送阵":       "Teleporter",
+		"怪物":        "Monster",
+		"编辑器 - ":    "Editor - ",
+		"已读取":       "Loaded ",
+		"读取失败：":     "Load failed: ",
+		"校验失败：":     "Invalid: ",
+		"校验通过":      "Valid",
+		"校验失败，未保存：": "Invalid, not saved: ",
+		"保存失败：":     "Save failed: ",
+		"已保存到":      "Saved to ",
+		"当前工具：":     "Tool: ",
+		"（1石头 2血池 3传送阵 4怪物 N加怪物 R转向 V校验 S保存）": " (1 stone 2 pool 3 teleporter 4 monster N add monster R rotate V validate S save)",
+		"最多只能有%d个怪物":   "At most %d monsters",
+		"把鼠标移到空地上再按N键": "Move the mouse to an empty cell and press N",
+
+		// 校验错误
+		"牌堆至少需要2张牌":       "the deck needs at least 2 cards",
+		"牌%s的步数和杀人数必须大于0": "card %s needs positive steps and kills",
+		"牌%s的效果%s不存在":     "card %s has unknown effect %s",
+		"棋盘大小不合法":         "invalid board size",
+		"切角大小不合法":         "invalid corner cut",
+		"入口或出口不合法":        "invalid entrance or exit",
+		"入口必须在棋盘边缘":       "the entrance must be on the edge",
+		"出口必须在棋盘边缘":       "the exit must be on the edge",
+		"怪物位置%v超出棋盘":      "monster %v is off the board",
+		"怪物朝向不合法":         "invalid monster direction",
+		"血池位置%v超出棋盘":      "blood pool %v is off the board",
+		"传送阵位置%v超出棋盘":     "teleporter %v is off the board",
+		"石头位置%v超出棋盘":      "stone %v is off the board",
+		"石头%v不能放在传送阵上":    "stone %v can't be on a teleporter",
+		"怪物%v不能和石头重叠":     "monster %v overlaps a stone",
+		"%v有两个怪物":         "two monsters at %v",
+		"入口被堵住了":          "the entrance is blocked",
+		"出口被堵住了":          "the exit is blocked",
+		"从入口无法到达出口":       "the exit can't be reached from the entrance",
+	}},
+}
+
+var latinFont *opentype.Font
+
+func init() {
+	var err error
+	latinFont, err = opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func currentLocale() *locale {
+	for _, l := range locales {
+		if l.Code == gameSettings.Language {
+			return l
+		}
+	}
+	return locales[0]
+}
+
+// tr 翻译一条消息
+func tr(s string) string {
+	if msg, ok := currentLocale().messages[s]; ok {
+		return msg
+	}
+	return s
+}
+
+// trf 翻译格式字符串后再格式化
+func trf(format string, a ...interface{}) string {
+	return fmt.Sprintf(tr(format), a...)
+}
+
+// fonts 返回当前语言使用的字体，排在前面的优先
+func (l *locale) fonts() []*opentype.Font {
+	if l.latinFirst {
+		return []*opentype.Font{latinFont, ttFont}
+	}
+	return []*opentype.Font{ttFont, latinFont}
+}
+
+// fallbackFace 对每个字依次尝试各个字体，用第一个有这个字形的字体来画
+type fallbackFace struct {
+	fonts []*opentype.Font
+	faces []font.Face
+	buf   sfnt.Buffer
+}
+
+func newFallbackFace(fonts []*opentype.Font, opts *opentype.FaceOptions) (*fallbackFace, error) {
+	f := &fallbackFace{fonts: fonts}
+	for _, fnt := range fonts {
+		face, err := opentype.NewFace(fnt, opts)
+		if err != nil {
+			return nil, err
+		}
+		f.faces = append(f.faces, face)
+	}
+	return f, nil
+}
+
+func (f *fallbackFace) pick(r rune) font.Face {
+	for i, fnt := range f.fonts {
+		if idx, err := fnt.GlyphIndex(&f.buf, r); err == nil && idx != 0 {
+			return f.faces[i]
+		}
+	}
+	return f.faces[0]
+}
+
+func (f *fallbackFace) Close() error {
+	for _, face := range f.faces {
+		_ = face.Close()
+	}
+	return nil
+}
+
+func (f *fallbackFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	return f.pick(r).Glyph(dot, r)
+}
+
+func (f *fallbackFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	return f.pick(r).GlyphBounds(r)
+}
+
+func (f *fallbackFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return f.pick(r).GlyphAdvance(r)
+}
+
+func (f *fallbackFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	if face := f.pick(r0); face == f.pick(r1) {
+		return face.Kern(r0, r1)
+	}
+	return 0
+}
+
+func (f *fallbackFace) Metrics() font.Metrics {
+	return f.faces[0].Metrics()
+}
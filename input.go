@@ -0,0 +1,97 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// action 是游戏中的操作，按键和手柄按钮都先映射成操作再交给游戏处理
+type action string
+
+const (
+	actionUp        action = "up"
+	actionDown      action = "down"
+	actionLeft      action = "left"
+	actionRight     action = "right"
+	actionConfirm   action = "confirm"
+	actionCancel    action = "cancel"
+	actionNextPiece action = "next_piece"
+	actionPrevPiece action = "prev_piece"
+	actionMute      action = "mute"
+	actionInfo      action = "info"
+	actionExplain   action = "explain"
+	actionDump      action = "dump"
+)
+
+// actionPiece 选择步数为step的棋子
+func actionPiece(step int) action {
+	return action("piece" + string(rune('0'+step)))
+}
+
+// keyBindings 保存在settings.json中，可以修改成别的按键，按键的名字和ebiten.Key的名字一致
+type keyBindings map[action][]ebiten.Key
+
+func defaultKeyBindings() keyBindings {
+	k := keyBindings{
+		actionUp:        {ebiten.KeyArrowUp, ebiten.KeyW, ebiten.KeyNumpad8},
+		actionDown:      {ebiten.KeyArrowDown, ebiten.KeyS, ebiten.KeyNumpad2},
+		actionLeft:      {ebiten.KeyArrowLeft, ebiten.KeyA, ebiten.KeyNumpad4},
+		actionRight:     {ebiten.KeyArrowRight, ebiten.KeyD, ebiten.KeyNumpad6},
+		actionConfirm:   {ebiten.KeyEnter, ebiten.KeyNumpadEnter, ebiten.KeySpace},
+		actionCancel:    {ebiten.KeyEscape, ebiten.KeyBackspace},
+		actionNextPiece: {ebiten.KeyTab, ebiten.KeyE},
+		actionPrevPiece: {ebiten.KeyQ},
+		actionMute:      {ebiten.KeyM},
+		actionInfo:      {ebiten.KeyI},
+		actionExplain:   {ebiten.KeyX},
+		actionDump:      {ebiten.KeyF12},
+	}
+	for i, key := range []ebiten.Key{ebiten.KeyDigit1, ebiten.KeyDigit2, ebiten.KeyDigit3, ebiten.KeyDigit4, ebiten.KeyDigit5, ebiten.KeyDigit6} {
+		k[actionPiece(i+1)] = []ebiten.Key{key}
+	}
+	return k
+}
+
+// gamepadBindings 标准布局手柄的按钮，十字键移动，A确定，B撤销，LB/RB切换棋子，Y查看棋子
+var gamepadBindings = map[action]ebiten.StandardGamepadButton{
+	actionUp:        ebiten.StandardGamepadButtonLeftTop,
+	actionDown:      ebiten.StandardGamepadButtonLeftBottom,
+	actionLeft:      ebiten.StandardGamepadButtonLeftLeft,
+	actionRight:     ebiten.StandardGamepadButtonLeftRight,
+	actionConfirm:   ebiten.StandardGamepadButtonRightBottom,
+	actionCancel:    ebiten.StandardGamepadButtonRightRight,
+	actionNextPiece: ebiten.StandardGamepadButtonFrontTopRight,
+	actionPrevPiece: ebiten.StandardGamepadButtonFrontTopLeft,
+	actionMute:      ebiten.StandardGamepadButtonCenterLeft,
+	actionInfo:      ebiten.StandardGamepadButtonRightTop,
+}
+
+var gamepadIDs []ebiten.GamepadID
+
+// inputFilter 不为nil时只有它允许的操作才有效，教程用它限制每一步能做的事
+var inputFilter func(a action) bool
+
+// isJustPressed 判断这一帧是否按下了某个操作对应的任意按键或手柄按钮
+func isJustPressed(a action) bool {
+	if inputFilter != nil && !inputFilter(a) {
+		return false
+	}
+	keys, ok := gameSettings.Keys[a]
+	if !ok {
+		keys = defaultKeyBindings()[a]
+	}
+	for _, k := range keys {
+		if inpututil.IsKeyJustPressed(k) {
+			return true
+		}
+	}
+	if button, ok := gamepadBindings[a]; ok {
+		gamepadIDs = ebiten.AppendGamepadIDs(gamepadIDs[:0])
+		for _, id := range gamepadIDs {
+			if ebiten.IsStandardGamepadLayoutAvailable(id) && inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+				return true
+			}
+		}
+	}
+	return false
+}
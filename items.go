@@ -9,11 +9,13 @@ import (
 )
 
 type itemInterface interface {
-	Draw() (*ebiten.Image, *ebiten.DrawImageOptions)
+	Draw(g *geometry) (*ebiten.Image, *ebiten.DrawImageOptions)
 	init(b *board)
 	tryMove(b *board, d dir) bool
 	forceMove(b *board, d dir)
 	setPos(pos point)
+	updateAnim()
+	drawn() (float64, float64)
 }
 
 var (
@@ -27,6 +29,7 @@ var (
 
 type stoneRegular struct {
 	pos point
+	animPos
 }
 
 func init() {
@@ -37,17 +40,18 @@ func init() {
 	imgStone = ebiten.NewImageFromImage(imageStone)
 }
 
-func (i *stoneRegular) Draw() (*ebiten.Image, *ebiten.DrawImageOptions) {
+func (i *stoneRegular) Draw(g *geometry) (*ebiten.Image, *ebiten.DrawImageOptions) {
 	opt := &ebiten.DrawImageOptions{}
 	opt.GeoM.Scale(0.2, 0.25)
 	opt.GeoM.Translate(8, 6)
+	opt.GeoM.Scale(g.scale(), g.scale())
 	return imgStone, opt
 }
 
 func (i *stoneRegular) init(b *board) {
 	for {
-		x, y := b.random.Intn(width), b.random.Intn(height)
-		if x < 3 && y < 3 || x >= width-1 && y >= height-1 || y-x >= height-3 || x-y >= width-3 {
+		x, y := b.random.Intn(b.geo.Width), b.random.Intn(b.geo.Height)
+		if pos := (point{x, y}); b.geo.outOfRange(pos) || b.geo.nearEntrance(pos) || pos == b.geo.Exit {
 			continue
 		}
 		if b.items[y][x] != nil {
@@ -66,7 +70,7 @@ func (i *stoneRegular) tryMove(b *board, d dir) bool {
 	pos := i.pos
 	pos.x += d.x
 	pos.y += d.y
-	if pos.outOfRange() || b.monster.pos == pos || b.floorShape[pos.y][pos.x] >= floorShapeTypeTransferUp || b.items[pos.y][pos.x] != nil {
+	if b.geo.outOfRange(pos) || b.monsterAt(pos) != nil || b.floorShape[pos.y][pos.x] >= floorShapeTypeTransferUp || b.items[pos.y][pos.x] != nil {
 		return false
 	}
 	for _, player := range b.player {
@@ -80,19 +84,21 @@ func (i *stoneRegular) tryMove(b *board, d dir) bool {
 	b.items[pos.y][pos.x] = i
 	i.pos = pos
 	if b.floorShape[pos.y][pos.x] == floorShapeTypeSlipFloor {
+		playSound(soundSlide)
 		i.tryMove(b, d)
 	}
-	if i.pos.x == width-1 && i.pos.y == height-1 {
+	if i.pos == b.geo.Exit {
 		b.items[i.pos.y][i.pos.x] = nil
 	}
 	return true
 }
 
 func (i *stoneRegular) forceMove(b *board, d dir) {
+	playSound(soundPush)
 	pos := i.pos
 	pos.x += d.x
 	pos.y += d.y
-	if pos.outOfRange() || b.monster.pos == pos || b.floorShape[pos.y][pos.x] >= floorShapeTypeTransferUp {
+	if b.geo.outOfRange(pos) || b.monsterAt(pos) != nil || b.floorShape[pos.y][pos.x] >= floorShapeTypeTransferUp {
 		b.items[i.pos.y][i.pos.x] = nil
 		return
 	}
@@ -113,6 +119,7 @@ func (i *stoneRegular) forceMove(b *board, d dir) {
 	b.items[pos.y][pos.x] = i
 	i.pos = pos
 	if b.floorShape[pos.y][pos.x] == floorShapeTypeSlipFloor {
+		playSound(soundSlide)
 		i.tryMove(b, d)
 	}
 }
@@ -120,3 +127,7 @@ func (i *stoneRegular) forceMove(b *board, d dir) {
 func (i *stoneRegular) setPos(pos point) {
 	i.pos = pos
 }
+
+func (i *stoneRegular) updateAnim() {
+	i.animate(i.pos)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type layout struct {
+	Geometry    geometry `json:"geometry"`
+	Stones      []point  `json:"stones"`
+	SlipFloors  []point  `json:"slip_floors"`
+	Teleporters []point  `json:"teleporters"`
+	Monster     point    `json:"monster"`
+	MonsterFace dir      `json:"monster_face"`
+	// Monsters 不为空时是所有怪物的位置和朝向，忽略Monster和MonsterFace
+	Monsters []layoutMonster `json:"monsters,omitempty"`
+}
+
+type layoutMonster struct {
+	Pos  point `json:"pos"`
+	Face dir   `json:"face"`
+}
+
+// monsters 布局中所有的怪物，旧的布局文件只有一个怪物
+func (l *layout) monsters() []layoutMonster {
+	if len(l.Monsters) > 0 {
+		return l.Monsters
+	}
+	return []layoutMonster{{l.Monster, l.MonsterFace}}
+}
+
+// loadLayout 读取布局文件，文件中没有geometry时使用geo，也就是-width、-height、-cut参数指定的棋盘
+func loadLayout(file string, geo *geometry) (*layout, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	l := &layout{}
+	if err = json.Unmarshal(buf, l); err != nil {
+		return nil, err
+	}
+	if l.Geometry.Width == 0 {
+		l.Geometry = *geo
+	}
+	l.Geometry.init()
+	if err = l.validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *layout) save(file string) error {
+	buf, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf, 0644)
+}
+
+func (l *layout) validate() error {
+	if err := l.Geometry.validate(); err != nil {
+		return err
+	}
+	return newEmptyBoard(&l.Geometry).applyLayout(l)
+}
+
+// applyLayout 摆好布局，第二个及以后的怪物和第一个怪物共用牌堆
+func (b *board) applyLayout(l *layout) error {
+	monsters := l.monsters()
+	if len(monsters) > maxMonsters {
+		return fmt.Errorf(tr("最多只能有%d个怪物"), maxMonsters)
+	}
+	for _, m := range monsters {
+		if b.geo.outOfRange(m.Pos) {
+			return fmt.Errorf(tr("怪物位置%v超出棋盘"), m.Pos)
+		}
+		if m.Face != up && m.Face != down && m.Face != left && m.Face != right {
+			return errors.New(tr("怪物朝向不合法"))
+		}
+	}
+	for _, p := range l.SlipFloors {
+		if b.geo.outOfRange(p) {
+			return fmt.Errorf(tr("血池位置%v超出棋盘"), p)
+		}
+		b.floorShape[p.y][p.x] = floorShapeTypeSlipFloor
+	}
+	for _, p := range l.Teleporters {
+		if b.geo.outOfRange(p) {
+			return fmt.Errorf(tr("传送阵位置%v超出棋盘"), p)
+		}
+		b.floorShape[p.y][p.x] = floorShapeTypeTransferUp
+	}
+	for _, p := range l.Stones {
+		if b.geo.outOfRange(p) {
+			return fmt.Errorf(tr("石头位置%v超出棋盘"), p)
+		}
+		if b.floorShape[p.y][p.x] >= floorShapeTypeTransferUp {
+			return fmt.Errorf(tr("石头%v不能放在传送阵上"), p)
+		}
+		b.items[p.y][p.x] = &stoneRegular{pos: p}
+	}
+	b.monsters = b.monsters[:1]
+	for i, lm := range monsters {
+		if b.items[lm.Pos.y][lm.Pos.x] != nil {
+			return fmt.Errorf(tr("怪物%v不能和石头重叠"), lm.Pos)
+		}
+		if b.monsterAt(lm.Pos) != nil && i > 0 {
+			return fmt.Errorf(tr("%v有两个怪物"), lm.Pos)
+		}
+		m := b.monsters[0]
+		if i > 0 {
+			m = newMonster(b.geo)
+			m.index, m.pile = i, b.monsters[0].pile
+			b.monsters = append(b.monsters, m)
+		}
+		m.pos, m.faceTo = lm.Pos, lm.Face
+		m.teleport(lm.Pos)
+	}
+	return b.checkReachable()
+}
+
+// toLayout 只有一个怪物时仍然用monster和monster_face保存，兼容旧的布局文件
+func (b *board) toLayout() *layout {
+	l := &layout{Geometry: *b.geo, Monster: b.monsters[0].pos, MonsterFace: b.monsters[0].faceTo}
+	if len(b.monsters) > 1 {
+		for _, m := range b.monsters {
+			l.Monsters = append(l.Monsters, layoutMonster{m.pos, m.faceTo})
+		}
+	}
+	for i := range b.items {
+		for j := range b.items[i] {
+			p := point{j, i}
+			if b.items[i][j] != nil {
+				l.Stones = append(l.Stones, p)
+			}
+			switch b.floorShape[i][j] {
+			case floorShapeTypeSlipFloor:
+				l.SlipFloors = append(l.SlipFloors, p)
+			case floorShapeTypeTransferUp:
+				l.Teleporters = append(l.Teleporters, p)
+			}
+		}
+	}
+	return l
+}
+
+// checkReachable 检查入口到出口之间是否有一条不经过石头和传送阵的路
+func (b *board) checkReachable() error {
+	start, exit := b.geo.Entrance, b.geo.Exit
+	blocked := func(p point) bool {
+		return b.items[p.y][p.x] != nil || b.floorShape[p.y][p.x] >= floorShapeTypeTransferUp
+	}
+	if blocked(start) {
+		return errors.New(tr("入口被堵住了"))
+	}
+	if blocked(exit) {
+		return errors.New(tr("出口被堵住了"))
+	}
+	visited := map[point]bool{start: true}
+	queue := []point{start}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == exit {
+			return nil
+		}
+		for _, d := range []dir{up, left, down, right} {
+			next := point{p.x + d.x, p.y + d.y}
+			if b.geo.outOfRange(next) || visited[next] || blocked(next) {
+				continue
+			}
+			visited[next] = true
+			queue = append(queue, next)
+		}
+	}
+	return errors.New(tr("从入口无法到达出口"))
+}
+
+func (p point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{p.x, p.y})
+}
+
+func (p *point) UnmarshalJSON(buf []byte) error {
+	var a [2]int
+	if err := json.Unmarshal(buf, &a); err != nil {
+		return err
+	}
+	p.x, p.y = a[0], a[1]
+	return nil
+}
+
+func (d dir) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *dir) UnmarshalJSON(buf []byte) error {
+	var s string
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return err
+	}
+	for _, v := range []dir{up, left, down, right} {
+		if v.String() == s {
+			*d = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown direction: %s", s)
+}
+
+func (d dir) String() string {
+	switch d {
+	case up:
+		return "up"
+	case left:
+		return "left"
+	case down:
+		return "down"
+	case right:
+		return "right"
+	}
+	return fmt.Sprintf("(%d,%d)", d.x, d.y)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLayoutMonsters(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    []layoutMonster
+		wantErr bool
+	}{
+		{"旧的布局只有一个怪物", `{"monster": [6, 5], "monster_face": "right"}`, []layoutMonster{{point{6, 5}, right}}, false},
+		{"几个怪物", `{"monsters": [{"pos": [14, 9], "face": "left"}, {"pos": [7, 4], "face": "up"}]}`, []layoutMonster{{point{14, 9}, left}, {point{7, 4}, up}}, false},
+		{"两个怪物在同一格", `{"monsters": [{"pos": [7, 4], "face": "left"}, {"pos": [7, 4], "face": "up"}]}`, nil, true},
+		{"怪物在石头上", `{"stones": [[7, 4]], "monsters": [{"pos": [14, 9], "face": "left"}, {"pos": [7, 4], "face": "up"}]}`, nil, true},
+		{"怪物太多", `{"monsters": [{"pos": [14, 9], "face": "left"}, {"pos": [7, 4], "face": "up"}, {"pos": [7, 5], "face": "up"}, {"pos": [7, 6], "face": "up"}]}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &layout{Geometry: *newGeometry(15, 10, 3)}
+			if err := json.Unmarshal([]byte(tt.json), l); err != nil {
+				t.Fatal(err)
+			}
+			b := newEmptyBoard(&l.Geometry)
+			err := b.applyLayout(l)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误是%v", err)
+			}
+			if err != nil {
+				return
+			}
+			if len(b.monsters) != len(tt.want) {
+				t.Fatalf("有%d个怪物", len(b.monsters))
+			}
+			for i, m := range b.monsters {
+				if m.index != i || m.pos != tt.want[i].Pos || m.faceTo != tt.want[i].Face || m.pile != b.monsters[0].pile {
+					t.Fatalf("第%d个怪物在%v朝%v", i+1, m.pos, m.faceTo)
+				}
+			}
+			// 保存后再读回来应该一样
+			b2 := newEmptyBoard(&l.Geometry)
+			if err = b2.applyLayout(b.toLayout()); err != nil {
+				t.Fatal(err)
+			}
+			for i, m := range b2.monsters {
+				if m.pos != b.monsters[i].pos || m.faceTo != b.monsters[i].faceTo {
+					t.Fatalf("保存后第%d个怪物在%v朝%v", i+1, m.pos, m.faceTo)
+				}
+			}
+		})
+	}
+}
+
+func TestAddMonsterNoFreeCell(t *testing.T) {
+	b := newEmptyBoard(newGeometry(4, 4, 0))
+	if !b.addMonster(true) {
+		t.Fatal("还有空地时应该能放怪物")
+	}
+	// 4×4的棋盘入口附近有9格，出口有第一个怪物，只剩6格空地
+	for b.addMonster(true) {
+		if len(b.monsters) > 16 {
+			t.Fatal("没有空地时还在放怪物")
+		}
+	}
+	if len(b.monsters) != 7 {
+		t.Fatalf("放了%d个怪物", len(b.monsters))
+	}
+	for _, m := range b.monsters {
+		if b.geo.nearEntrance(m.pos) {
+			t.Fatalf("怪物放在了入口附近%v", m.pos)
+		}
+	}
+}
+
+func TestLoadLayoutDefaultGeometry(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "layout.json")
+	if err := os.WriteFile(file, []byte(`{"monster": [7, 5], "monster_face": "left"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := loadLayout(file, newGeometry(8, 6, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := l.Geometry; g.Width != 8 || g.Height != 6 || g.Cut != 2 {
+		t.Fatalf("棋盘是%dx%d，切角%d", g.Width, g.Height, g.Cut)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
@@ -10,9 +11,66 @@ import (
 	"time"
 )
 
+var (
+	layoutFile = flag.String("layout", "", "布局文件，为空则随机生成")
+	editMode   = flag.Bool("edit", false, "以编辑器模式打开布局文件")
+	boardW     = flag.Int("width", 15, "棋盘宽度")
+	boardH     = flag.Int("height", 10, "棋盘高度")
+	boardCut   = flag.Int("cut", 3, "右上角和左下角切掉的格数")
+	rulesFile  = flag.String("rules", "", "自定义规则文件，会作为一个额外的选项出现在设置界面")
+	puzzleFile = flag.String("puzzle", "", "残局文件，不为空时直接开始这个残局")
+	position   = flag.String("position", "", "文字记法的棋盘文件，不为空时从这个局面开始游戏")
+)
+
 func main() {
-	g := newBoard(2)
-	ebiten.SetWindowSize(1024, 768)
+	flag.Parse()
+	loadSettings()
+	initAudio()
+	geo := newGeometry(*boardW, *boardH, *boardCut)
+	if err := geo.validate(); err != nil {
+		logger.WithError(err).Fatal("invalid geometry")
+	}
+	// 编辑器会换成自己的标题
+	ebiten.SetWindowTitle("Fearsome Floors")
+	var g ebiten.Game
+	if *editMode {
+		file := *layoutFile
+		if file == "" {
+			file = "layout.json"
+		}
+		g = newEditor(file, geo)
+	} else if *puzzleFile != "" {
+		p, err := loadPuzzle(*puzzleFile, geo)
+		if err != nil {
+			logger.WithError(err).Fatal("load puzzle failed")
+		}
+		g = newPuzzleBoard(p)
+	} else if *position != "" {
+		b, err := loadPosition(*position)
+		if err != nil {
+			logger.WithError(err).Fatal("load position failed")
+		}
+		g = b
+	} else {
+		var l *layout
+		if *layoutFile != "" {
+			var err error
+			if l, err = loadLayout(*layoutFile, geo); err != nil {
+				logger.WithError(err).Fatal("load layout failed")
+			}
+		}
+		presets := rulesPresets()
+		if *rulesFile != "" {
+			r, err := loadRules(*rulesFile)
+			if err != nil {
+				logger.WithError(err).Fatal("load rules failed")
+			}
+			presets = append(presets, r)
+		}
+		g = newSetup(&gameOptions{playerNum: 2, geo: geo, layout: l, monsterNum: 1, sharedDeck: true}, presets)
+	}
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowResizable(true)
 	if err := ebiten.RunGame(g); err != nil {
 		logger.Fatal(err)
 	}
@@ -5,10 +5,12 @@ import (
 	_ "embed"
 	"github.com/hajimehoshi/ebiten/v2"
 	"image"
+	"image/color"
 	"math"
-	"time"
 )
 
+const monsterStepFrames = 30
+
 //go:embed assets/monster.png
 var fileMonster []byte
 var imgMonster *ebiten.Image
@@ -22,20 +24,41 @@ func init() {
 }
 
 type monster struct {
+	// index 第几个怪物，用来区分多个怪物
+	index    int
 	isMoving bool
+	// waiting 这一轮还没轮到这个怪物移动，round是排队时的轮数，几个怪物依次移动时第一轮的规则对每个怪物都有效
+	waiting  bool
+	round    int
 	faceTo   dir
 	pos      point
-	deck     []*card
 	lastCard *card
+	decision *decision
+	brain    monsterBrain
+	drawnAt  int
+	leftStep int
+	kills    int
+
+	nextStepAt int
+	animPos
+	*pile
 }
 
-func (m *monster) Draw() (*ebiten.Image, *ebiten.DrawImageOptions) {
+// monsterTints 第二个及以后的怪物画成不同的颜色
+var monsterTints = []color.Color{
+	color.White,
+	color.RGBA{R: 0x80, G: 0xc0, B: 0xff, A: 0xff},
+	color.RGBA{R: 0xa0, G: 0xff, B: 0x80, A: 0xff},
+	color.RGBA{R: 0xff, G: 0xd0, B: 0x60, A: 0xff},
+}
+
+func (m *monster) Draw(g *geometry) (*ebiten.Image, *ebiten.DrawImageOptions) {
 	bounds := imgMonster.Bounds()
 	dx, dy := bounds.Dx(), bounds.Dy()
 	opt := &ebiten.DrawImageOptions{}
-	opt.GeoM.Scale(float64(gridLen)/float64(dx), float64(gridLen)/float64(dy))
+	opt.GeoM.Scale(tileSize/float64(dx), tileSize/float64(dy))
 	if m.faceTo != left {
-		opt.GeoM.Translate(-float64(gridLen)/2, -float64(gridLen)/2)
+		opt.GeoM.Translate(-tileSize/2, -tileSize/2)
 		switch m.faceTo {
 		case right:
 			opt.GeoM.Scale(-1, 1)
@@ -44,23 +67,44 @@ func (m *monster) Draw() (*ebiten.Image, *ebiten.DrawImageOptions) {
 		case down:
 			opt.GeoM.Rotate(-math.Pi / 2)
 		}
-		opt.GeoM.Translate(float64(gridLen)/2, float64(gridLen)/2)
+		opt.GeoM.Translate(tileSize/2, tileSize/2)
 	}
-	opt.GeoM.Translate(edgeX+1+float64(m.pos.x)*gridLen, edgeY+1+float64(m.pos.y)*gridLen)
+	opt.GeoM.Scale(g.scale(), g.scale())
+	opt.ColorM.ScaleWithColor(monsterTints[m.index%len(monsterTints)])
 	return imgMonster, opt
 }
 
-func (m *monster) moveOne(b *board, leftStep int, curKillCount, maxKillCount int) {
-	if leftStep == 0 {
+func (m *monster) update(b *board) {
+	m.animate(m.pos)
+	wasMoving := m.isMoving
+	for m.isMoving && b.tick >= m.nextStepAt {
+		m.moveOne(b)
+	}
+	if wasMoving && !m.isMoving {
+		m.checkExhausted(b)
+	}
+}
+
+func (m *monster) moveOne(b *board) {
+	if m.leftStep == 0 {
 		m.isMoving = false
 		return
 	}
 	pos := m.pos
 	pos.x += m.faceTo.x
 	pos.y += m.faceTo.y
-	if pos.outOfRange() {
-		pos.x = width - 1 - m.pos.x
-		pos.y = height - 1 - m.pos.y
+	if b.geo.outOfRange(pos) {
+		pos = b.geo.mirror(m.pos)
+		if b.monsterAt(pos) == nil {
+			m.teleport(pos)
+		}
+	}
+	if other := b.monsterAt(pos); other != nil {
+		// 撞到别的怪物时停下，剩下的步数作废
+		logger.WithField("monster", m.index).WithField("other", other.index).Info("monster blocked by another monster")
+		m.leftStep = 0
+		m.isMoving = false
+		return
 	}
 	if b.items[pos.y][pos.x] != nil {
 		b.items[pos.y][pos.x].forceMove(b, m.faceTo)
@@ -69,8 +113,9 @@ func (m *monster) moveOne(b *board, leftStep int, curKillCount, maxKillCount int
 		for _, item := range player.items {
 			if item.pos == pos {
 				item.die(b)
-				curKillCount++
-				if curKillCount == maxKillCount {
+				m.kills++
+				if m.kills == m.lastCard.Kills {
+					m.leftStep = 0
 					m.pos = pos
 					m.chooseDir(b)
 					m.isMoving = false
@@ -81,119 +126,239 @@ func (m *monster) moveOne(b *board, leftStep int, curKillCount, maxKillCount int
 	}
 	m.pos = pos
 	if b.floorShape[m.pos.y][m.pos.x] == floorShapeTypeSlipFloor {
-		m.moveOne(b, leftStep, curKillCount, maxKillCount)
+		playSound(soundSlide)
 		return
 	}
+	playSound(soundStep)
+	m.leftStep--
 	m.chooseDir(b)
-	time.AfterFunc(time.Second/2, func() { m.moveOne(b, leftStep-1, curKillCount, maxKillCount) })
+	if gameSettings.Animation {
+		m.nextStepAt = b.tick + monsterStepFrames
+	}
 }
 
 func (m *monster) move(b *board) {
 	m.isMoving = true
-	idx := b.random.Intn(len(m.deck))
-	if b.bigTurn == 0 {
-		for m.deck[idx].step >= 20 {
-			idx = b.random.Intn(len(m.deck))
-		}
-	}
+	c := m.draw(b)
+	m.lastCard = c
+	playSound(soundCard)
+	playSound(soundRoar)
+	m.drawnAt = b.tick
+	m.nextStepAt = b.tick
+	m.leftStep = c.Step
+	m.kills = 0
 	m.chooseDir(b)
-	m.moveOne(b, m.deck[idx].step, 0, m.deck[idx].kills)
-	m.deck = append(m.deck[:idx], m.deck[idx+1:]...)
-	if len(m.deck) <= 1 {
-		m.deck = newDeck()
+	switch c.Effect {
+	case cardEffectTurn:
+		m.faceTo = dir{-m.faceTo.x, -m.faceTo.y}
+	case cardEffectJump:
+		m.jump(b)
 	}
 }
 
-func (m *monster) chooseDir(b *board) {
-	leftDistance := m.findPlayer(b, left)
-	upDistance := m.findPlayer(b, up)
-	rightDistance := m.findPlayer(b, right)
-	downDistance := m.findPlayer(b, down)
-	min := leftDistance
-	if upDistance < min {
-		min = upDistance
-	}
-	if rightDistance < min {
-		min = rightDistance
-	}
-	if downDistance < min {
-		min = downDistance
-	}
-	minCount := 0
-	var minDir dir
-	if leftDistance == min {
-		minCount++
-		minDir = left
-	}
-	if rightDistance == min {
-		if minCount > 0 {
-			return
+// jump 怪物跳到棋盘上与当前位置中心对称的格子，如果那里有东西则不跳
+func (m *monster) jump(b *board) {
+	pos := b.geo.mirror(m.pos)
+	if b.items[pos.y][pos.x] != nil || b.floorShape[pos.y][pos.x] >= floorShapeTypeTransferUp || b.monsterAt(pos) != nil {
+		return
+	}
+	for _, player := range b.player {
+		for _, item := range player.items {
+			if item.pos == pos {
+				return
+			}
 		}
-		minCount++
-		minDir = right
 	}
-	if upDistance == min {
-		if minCount > 0 {
-			return
+	m.pos = pos
+	m.teleport(pos)
+	m.chooseDir(b)
+}
+
+// draw 从牌堆最上面抽一张牌放进弃牌堆
+func (m *monster) draw(b *board) *card {
+	if exclude := b.rules.FirstRoundExcludeStep; m.round == 0 && exclude > 0 && m.hasCardBelow(exclude) {
+		switch b.rules.FirstDraw {
+		case firstDrawBury:
+			m.deck = buryCards(m.deck, exclude)
+		default:
+			for m.deck[0].Step >= exclude {
+				shuffleDeck(b.random, m.deck)
+			}
 		}
-		minCount++
-		minDir = up
 	}
-	if downDistance == min {
-		if minCount > 0 {
-			return
+	c := m.deck[0]
+	m.deck = m.deck[1:]
+	m.discard = append(m.discard, c)
+	return c
+}
+
+// checkExhausted 怪物走完后，牌堆剩下的牌不多于ReshuffleAt张时算作用完一次，第一次用完时重新洗牌。
+// 要等怪物用最后一张牌走完再算，这张牌吃掉的棋子还属于上一个阶段
+func (m *monster) checkExhausted(b *board) {
+	if m.cycle >= 2 || len(m.deck) > b.rules.ReshuffleAt {
+		return
+	}
+	m.cycle++
+	logger.WithField("monster", m.index).WithField("cycle", m.cycle).Info("monster deck exhausted")
+	if m.cycle < 2 {
+		m.deck = append(m.deck, m.discard...)
+		shuffleDeck(b.random, m.deck)
+		m.discard = nil
+	}
+}
+
+func (m *monster) hasCardBelow(step int) bool {
+	for _, c := range m.deck {
+		if c.Step < step {
+			return true
 		}
-		minDir = down
 	}
-	m.faceTo = minDir
+	return false
 }
 
-func (m *monster) findPlayer(b *board, d dir) int {
+// chooseDir 记录四个方向上看到的东西，由怪物的AI决定朝哪个方向走
+func (m *monster) chooseDir(b *board) {
+	d := &decision{pos: m.pos, from: m.faceTo}
+	for i, dd := range decisionDirs {
+		d.sights[i] = m.findPlayer(b, dd)
+	}
+	m.faceTo = m.brain.chooseDir(m, b, d)
+	d.to = m.faceTo
+	m.decision = d
+	d.log()
+}
+
+// findPlayer 沿着d方向看过去，返回最近的棋子的距离，被石头或棋盘边缘挡住时距离为99
+func (m *monster) findPlayer(b *board, d dir) sight {
 	if m.faceTo.x+d.x == 0 && m.faceTo.y+d.y == 0 {
-		return 99
+		return sight{distance: 99, behind: true}
 	}
 	pos := m.pos
 	for i := 1; i < 99; i++ {
 		pos.x += d.x
 		pos.y += d.y
-		if pos.outOfRange() || b.items[pos.y][pos.x] != nil {
-			return 99
+		if b.geo.outOfRange(pos) {
+			return sight{distance: 99}
 		}
-		for _, player := range b.player {
-			for _, item := range player.items {
-				if item.pos == pos {
-					return i
-				}
-			}
+		if b.monsterAt(pos) != nil {
+			return sight{distance: 99, blocked: true}
+		}
+		if b.items[pos.y][pos.x] != nil {
+			return sight{distance: 99, blocked: true, hidden: b.piecesInLine(pos, d)}
+		}
+		if seen := b.piecesOn(pos); len(seen) > 0 {
+			return sight{distance: i, seen: seen}
 		}
 	}
 	logger.Fatal("unreachable code")
-	return 99
+	return sight{distance: 99}
 }
 
-func newMonster() *monster {
+func newMonster(geo *geometry) *monster {
 	return &monster{
 		faceTo: left,
-		pos:    point{width - 1, height - 1},
-		deck:   newDeck(),
+		pos:    geo.Exit,
+		brain:  monsterBrains[0],
+		pile:   &pile{},
 	}
 }
 
-type card struct {
-	text  string
-	step  int
-	kills int
+// maxMonsters 棋盘上最多放几个怪物
+const maxMonsters = 3
+
+// addMonster 在随机的空地上再放一个怪物，shared为true时和第一个怪物共用牌堆，否则用一副新牌。
+// 没有空地时不放，返回false
+func (b *board) addMonster(shared bool) bool {
+	g := b.geo
+	var free []point
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			pos := point{x, y}
+			if g.outOfRange(pos) || g.nearEntrance(pos) || b.items[y][x] != nil ||
+				b.floorShape[y][x] >= floorShapeTypeTransferUp || b.monsterAt(pos) != nil {
+				continue
+			}
+			free = append(free, pos)
+		}
+	}
+	if len(free) == 0 {
+		logger.WithField("monsters", len(b.monsters)).Warn("no free cell for another monster")
+		return false
+	}
+	m := newMonster(g)
+	m.index = len(b.monsters)
+	if shared {
+		m.pile = b.monsters[0].pile
+	} else {
+		m.deck = newDeck(b.random, b.rules.Deck)
+	}
+	m.pos = free[b.random.Intn(len(free))]
+	m.faceTo = decisionDirs[b.random.Intn(len(decisionDirs))]
+	m.teleport(m.pos)
+	b.monsters = append(b.monsters, m)
+	return true
 }
 
-func newDeck() []*card {
-	return []*card{
-		{"5", 5, 99},
-		{"7", 7, 99},
-		{"7", 7, 99},
-		{"8", 8, 99},
-		{"8", 8, 99},
-		{"10", 10, 99},
-		{"X", 20, 1},
-		{"XX", 20, 2},
+func (b *board) monsterAt(pos point) *monster {
+	for _, m := range b.monsters {
+		if m.pos == pos {
+			return m
+		}
+	}
+	return nil
+}
+
+// cycle 牌堆用完的次数，有几副牌时取用完次数最多的
+func (b *board) cycle() int {
+	cycle := 0
+	for _, m := range b.monsters {
+		if m.cycle > cycle {
+			cycle = m.cycle
+		}
+	}
+	return cycle
+}
+
+// monsterBusy 还有怪物正在移动或者等着移动
+func (b *board) monsterBusy() bool {
+	for _, m := range b.monsters {
+		if m.isMoving || m.waiting {
+			return true
+		}
+	}
+	return false
+}
+
+// startNextMonster 多个怪物按顺序一个接一个地移动，前一个走完后下一个才开始
+func (b *board) startNextMonster() {
+	for _, m := range b.monsters {
+		if m.isMoving {
+			return
+		}
+	}
+	for _, m := range b.monsters {
+		if !m.waiting {
+			continue
+		}
+		m.waiting = false
+		if b.cycle() >= 2 {
+			continue
+		}
+		m.move(b)
+		return
+	}
+}
+
+// activeMonster 正在移动的怪物，都没在移动时返回最后一个抽牌的怪物
+func (b *board) activeMonster() *monster {
+	active := b.monsters[0]
+	for _, m := range b.monsters {
+		if m.isMoving {
+			return m
+		}
+		if m.lastCard != nil && m.drawnAt > active.drawnAt {
+			active = m
+		}
 	}
+	return active
 }
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 棋盘的文字记法，每行是棋盘的一行，格子之间用空格分开：
+//
+//	.   空地
+//	x   切掉的角，必须正好在point.outOfRange的位置上
+//	#   石头
+//	~   血池
+//	@   传送阵
+//	M>  怪物和它的朝向，朝向可以是> < ^ v
+//	r3  红方步数为3的棋子，玩家依次是r g y b p o，大写表示这一轮已经走过
+//
+// 同一格有几个棋子时用+连起来，例如r3+b2。棋子、怪物或石头下面是血池或传送阵时在后面加上~或@，例如M>~。棋盘下面可以有start:、finish:、dead:三行列出托盘里的棋子，
+// 入口和出口不在默认位置时用entrance: x,y和exit: x,y指定
+const (
+	notationEmpty      = "."
+	notationCut        = "x"
+	notationStone      = "#"
+	notationSlipFloor  = "~"
+	notationTeleporter = "@"
+	notationMonster    = 'M'
+)
+
+var notationPlayers = "rgybpo"
+
+var notationDirs = map[byte]dir{'>': right, '<': left, '^': up, 'v': down}
+
+func notationDir(d dir) string {
+	for c, v := range notationDirs {
+		if v == d {
+			return string(c)
+		}
+	}
+	return "?"
+}
+
+func (p *playerItem) notation() string {
+	c := notationPlayers[p.shape]
+	if p.alreadyMove {
+		c -= 'a' - 'A'
+	}
+	return string(c) + strconv.Itoa(p.step)
+}
+
+// dump 用文字记法输出棋盘，可以用parseBoard读回来
+func (b *board) dump() string {
+	g := b.geo
+	cells := make(map[point][]string)
+	trays := make(map[trayKind][]string)
+	for _, p := range b.player {
+		for _, item := range p.items {
+			if k, ok := b.trayOf(item); ok {
+				trays[k] = append(trays[k], item.notation())
+			} else {
+				cells[item.pos] = append(cells[item.pos], item.notation())
+			}
+		}
+	}
+	for _, m := range b.monsters {
+		cells[m.pos] = append(cells[m.pos], string(notationMonster)+notationDir(m.faceTo))
+	}
+	var sb strings.Builder
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			pos := point{x, y}
+			var s string
+			switch {
+			case g.outOfRange(pos):
+				s = notationCut
+			case len(cells[pos]) > 0:
+				s = strings.Join(cells[pos], "+")
+			case b.items[y][x] != nil:
+				s = notationStone
+			}
+			if !g.outOfRange(pos) {
+				switch {
+				case b.floorShape[y][x] == floorShapeTypeSlipFloor:
+					s += notationSlipFloor
+				case b.floorShape[y][x] >= floorShapeTypeTransferUp:
+					s += notationTeleporter
+				}
+			}
+			if s == "" {
+				s = notationEmpty
+			}
+			if x > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(s)
+		}
+		sb.WriteByte('\n')
+	}
+	for k, name := range []string{"start", "finish", "dead"} {
+		if items := trays[trayKind(k)]; len(items) > 0 {
+			fmt.Fprintf(&sb, "%s: %s\n", name, strings.Join(items, " "))
+		}
+	}
+	if g.Entrance != (point{0, 0}) {
+		fmt.Fprintf(&sb, "entrance: %d,%d\n", g.Entrance.x, g.Entrance.y)
+	}
+	if g.Exit != (point{g.Width - 1, g.Height - 1}) {
+		fmt.Fprintf(&sb, "exit: %d,%d\n", g.Exit.x, g.Exit.y)
+	}
+	return sb.String()
+}
+
+// parseBoard 读取文字记法的棋盘，棋盘的宽高由行列数决定，切角的大小由第一行末尾x的个数决定
+func parseBoard(s string) (*board, error) {
+	var rows [][]string
+	extra := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			extra[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+			continue
+		}
+		rows = append(rows, strings.Fields(line))
+	}
+	if len(rows) == 0 {
+		return nil, errors.New(tr("棋盘是空的"))
+	}
+	width, height := len(rows[0]), len(rows)
+	cut := 0
+	for i := width - 1; i >= 0 && rows[0][i] == notationCut; i-- {
+		cut++
+	}
+	g := newGeometry(width, height, cut)
+	for key, target := range map[string]*point{"entrance": &g.Entrance, "exit": &g.Exit} {
+		if v, ok := extra[key]; ok {
+			if _, err := fmt.Sscanf(v, "%d,%d", &target.x, &target.y); err != nil {
+				return nil, fmt.Errorf(tr("%s格式不对：%s"), key, v)
+			}
+		}
+	}
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	b := newEmptyBoard(g)
+	b.monsters = nil
+	var pieces [maxPlayers][]*playerItem
+	addPiece := func(token string, pos point) error {
+		if len(token) < 2 {
+			return fmt.Errorf(tr("看不懂%q"), token)
+		}
+		moved := token[0] >= 'A' && token[0] <= 'Z'
+		i := strings.IndexByte(notationPlayers, strings.ToLower(token[:1])[0])
+		step, err := strconv.Atoi(token[1:])
+		if i < 0 || err != nil || step < 1 || step > 20 {
+			return fmt.Errorf(tr("看不懂%q"), token)
+		}
+		pieces[i] = append(pieces[i], &playerItem{step: step, pos: pos, alreadyMove: moved, shape: i})
+		return nil
+	}
+	for y, row := range rows {
+		if len(row) != width {
+			return nil, fmt.Errorf(tr("第%d行有%d格，应该有%d格"), y+1, len(row), width)
+		}
+		for x, cell := range row {
+			pos := point{x, y}
+			if g.outOfRange(pos) != (cell == notationCut) {
+				return nil, fmt.Errorf(tr("切角和第%d行第%d格不一致"), y+1, x+1)
+			}
+			switch {
+			case strings.HasSuffix(cell, notationSlipFloor):
+				b.floorShape[y][x] = floorShapeTypeSlipFloor
+				cell = strings.TrimSuffix(cell, notationSlipFloor)
+			case strings.HasSuffix(cell, notationTeleporter):
+				b.floorShape[y][x] = floorShapeTypeTransferUp
+				cell = strings.TrimSuffix(cell, notationTeleporter)
+			}
+			switch cell {
+			case "", notationEmpty, notationCut:
+			case notationStone:
+				b.items[y][x] = &stoneRegular{pos: pos}
+			default:
+				for _, token := range strings.Split(cell, "+") {
+					if len(token) == 2 && token[0] == notationMonster {
+						d, ok := notationDirs[token[1]]
+						if !ok {
+							return nil, fmt.Errorf(tr("看不懂%q"), token)
+						}
+						m := newMonster(g)
+						m.index, m.pos, m.faceTo = len(b.monsters), pos, d
+						if len(b.monsters) > 0 {
+							m.pile = b.monsters[0].pile
+						}
+						m.teleport(pos)
+						b.monsters = append(b.monsters, m)
+					} else if err := addPiece(token, pos); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	for k, name := range []string{"start", "finish", "dead"} {
+		pos := [...]point{g.startPos(), g.finishPos(), g.deadPos()}[k]
+		for _, token := range strings.Fields(extra[name]) {
+			if err := addPiece(token, pos); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(b.monsters) == 0 {
+		b.monsters = []*monster{newMonster(g)}
+	}
+	b.monsters[0].deck = newDeck(b.random, b.rules.Deck)
+	for i := range pieces {
+		if len(pieces[i]) == 0 {
+			continue
+		}
+		for len(b.player) <= i {
+			n := len(b.player)
+			b.player = append(b.player, &player{text: tr(playerNames[n]), color: playerColor(n), shape: n})
+		}
+		p := b.player[i]
+		for n, item := range pieces[i] {
+			item.id = fmt.Sprintf("%c%d", 'A'+i, n+1)
+			item.color = p.color
+			item.teleport(item.pos)
+			p.items = append(p.items, item)
+		}
+	}
+	return b, nil
+}
+
+// loadPosition 读取文字记法的棋盘文件，从这个局面开始游戏
+func loadPosition(file string) (*board, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	b, err := parseBoard(string(buf))
+	if err != nil {
+		return nil, err
+	}
+	if len(b.player) == 0 {
+		return nil, errors.New(tr("棋盘上没有棋子"))
+	}
+	b.bigTurn = 1
+	if !b.player[0].hasItemToMove(b) {
+		b.nextPlayer()
+	}
+	return b, nil
+}
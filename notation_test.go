@@ -0,0 +1,144 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNotationRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		board string
+	}{
+		{
+			name: "切角、托盘、叠在一起的棋子和多个怪物",
+			board: "r3 . ~ # x x\n" +
+				". r3+b2 . . M>~ x\n" +
+				". . @ . . .\n" +
+				"x . B5 . M^ .\n" +
+				"x x . Mv . .\n" +
+				"start: r1 g2\n" +
+				"finish: b3\n" +
+				"dead: y4\n",
+		},
+		{
+			name: "入口和出口不在默认位置",
+			board: ". . . . M<\n" +
+				". . #~ . .\n" +
+				"r2 . . . .\n" +
+				". . . o6@ .\n" +
+				". . . . .\n" +
+				"entrance: 0,2\n" +
+				"exit: 4,0\n",
+		},
+		{
+			name:  "没有棋子时怪物在出口",
+			board: ". . . .\n. . . .\n. . . .\n. . . M<\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := parseBoard(tt.board)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dumped := b.dump()
+			if dumped != tt.board {
+				t.Fatalf("dump不一致：\n%s\n应该是：\n%s", dumped, tt.board)
+			}
+			b2, err := parseBoard(dumped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again := b2.dump(); again != dumped {
+				t.Fatalf("第二次dump不一致：\n%s\n应该是：\n%s", again, dumped)
+			}
+		})
+	}
+}
+
+func TestParseBoard(t *testing.T) {
+	b, err := parseBoard("r3 . ~ # x x\n" +
+		". r3+b2 . . M>~ x\n" +
+		". . @ . . .\n" +
+		"x . B5 . M^ .\n" +
+		"x x . Mv . .\n" +
+		"start: r1 g2\n" +
+		"finish: b3\n" +
+		"dead: y4\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := b.geo
+	if g.Width != 6 || g.Height != 5 || g.Cut != 2 {
+		t.Fatalf("棋盘大小是%dx%d，切角%d", g.Width, g.Height, g.Cut)
+	}
+	if b.items[0][3] == nil || b.floorShape[0][2] != floorShapeTypeSlipFloor || b.floorShape[2][2] != floorShapeTypeTransferUp {
+		t.Fatal("石头、血池或传送阵的位置不对")
+	}
+	if len(b.monsters) != 3 {
+		t.Fatalf("有%d个怪物", len(b.monsters))
+	}
+	m := b.monsters[0]
+	if m.pos != (point{4, 1}) || m.faceTo != right || b.floorShape[1][4] != floorShapeTypeSlipFloor {
+		t.Fatalf("第一个怪物在%v朝%v", m.pos, m.faceTo)
+	}
+	if b.monsters[1].faceTo != up || b.monsters[2].faceTo != down || b.monsters[1].pile != m.pile {
+		t.Fatal("其余怪物的朝向或牌堆不对")
+	}
+	want := map[string]struct {
+		step  int
+		pos   point
+		moved bool
+	}{
+		"A1": {3, point{0, 0}, false},
+		"A2": {3, point{1, 1}, false},
+		"A3": {1, g.startPos(), false},
+		"B1": {2, g.startPos(), false},
+		"C1": {4, g.deadPos(), false},
+		"D1": {2, point{1, 1}, false},
+		"D2": {5, point{2, 3}, true},
+		"D3": {3, g.finishPos(), false},
+	}
+	count := 0
+	for _, p := range b.player {
+		for _, item := range p.items {
+			count++
+			w, ok := want[item.id]
+			if !ok {
+				t.Fatalf("多了棋子%s", item.id)
+			}
+			if item.step != w.step || item.pos != w.pos || item.alreadyMove != w.moved {
+				t.Fatalf("棋子%s是%d步，在%v，已走%t", item.id, item.step, item.pos, item.alreadyMove)
+			}
+		}
+	}
+	if count != len(want) || len(b.player) != 4 {
+		t.Fatalf("有%d个玩家%d个棋子", len(b.player), count)
+	}
+}
+
+func TestParseBoardErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		board string
+	}{
+		{"空棋盘", "\n\n"},
+		{"行的格数不一样", ". . . .\n. . .\n. . . .\n. . . .\n"},
+		{"切角不一致", ". . . .\n. . . .\n. . x .\n. . . .\n"},
+		{"切角处不是x", ". . x x\n. . . x\n. . . .\n. . . .\n"},
+		{"不认识的玩家", ". . . .\n. q3 . .\n. . . .\n. . . .\n"},
+		{"步数不对", ". . . .\n. r0 . .\n. . . .\n. . . .\n"},
+		{"只有玩家没有步数", ". . . .\n. r . .\n. . . .\n. . . .\n"},
+		{"怪物朝向不对", ". . . .\n. M? . .\n. . . .\n. . . .\n"},
+		{"托盘里的棋子不对", ". . . .\n. . . .\n. . . .\n. . . .\nstart: r1 zz\n"},
+		{"出口格式不对", ". . . .\n. . . .\n. . . .\n. . . .\nexit: 3\n"},
+		{"出口不在边缘", ". . . . .\n. . . . .\n. . . . .\n. . . . .\n. . . . .\nexit: 2,2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseBoard(tt.board); err == nil {
+				t.Fatal("应该返回错误")
+			}
+		})
+	}
+}
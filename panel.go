@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	cardWidth    = 120
+	cardHeight   = 170
+	revealFrames = 30
+)
+
+func (b *board) drawCardPanel(screen *ebiten.Image) {
+	m := b.activeMonster()
+	t := theView.theme
+	x := float64(theView.width) - ui(panelWidth)
+	drawRect(screen, x, 0, ui(2), float64(theView.height), t.Grid)
+	x += ui(20)
+	title := tr("怪物牌")
+	if len(b.monsters) > 1 {
+		title = trf("%d号怪物的牌", m.index+1)
+	}
+	text.Draw(screen, title, fontAlpha, int(x), uiInt(40), t.Text)
+
+	cardX, cardY, cardW, cardH := x+ui(panelWidth-40-cardWidth)/2, ui(60), ui(cardWidth), ui(cardHeight)
+	if m.lastCard == nil {
+		drawRect(screen, cardX, cardY, cardW, cardH, t.CardBack)
+	} else {
+		// 翻牌动画：前一半时间牌背逐渐变窄，后一半时间牌面逐渐变宽
+		progress := math.Min(1, float64(b.tick-m.drawnAt)/revealFrames)
+		if !gameSettings.Animation {
+			progress = 1
+		}
+		w := cardW * math.Abs(math.Cos(progress*math.Pi))
+		if progress < 0.5 {
+			drawRect(screen, cardX+(cardW-w)/2, cardY, w, cardH, t.CardBack)
+		} else {
+			border := ui(4)
+			drawRect(screen, cardX+(cardW-w)/2, cardY, w, cardH, t.CardText)
+			drawRect(screen, cardX+(cardW-w)/2+border, cardY+border, math.Max(0, w-2*border), cardH-2*border, t.CardFace)
+			if progress >= 1 {
+				bounds := text.BoundString(fontNum, tr(m.lastCard.Text))
+				text.Draw(screen, tr(m.lastCard.Text), fontNum, int(cardX+(cardW-float64(bounds.Dx()))/2), int(cardY+(cardH+float64(bounds.Dy()))/2), t.CardText)
+			}
+		}
+	}
+
+	y := uiInt(60 + cardHeight + 40)
+	if m.isMoving {
+		kills := tr("不限")
+		if m.lastCard.Kills < 99 {
+			kills = strconv.Itoa(m.lastCard.Kills - m.kills)
+		}
+		text.Draw(screen, trf("剩余步数：%d", m.leftStep), fontSmall, int(x), y, t.Text)
+		y += uiInt(26)
+		text.Draw(screen, tr("还能吃掉：")+kills, fontSmall, int(x), y, t.Text)
+		y += uiInt(26)
+	}
+	y += uiInt(14)
+	text.Draw(screen, trf("牌堆剩余%d张", len(m.deck)), fontSmall, int(x), y, t.Text)
+	y += uiInt(26)
+	for _, s := range deckCounts(m.deck) {
+		text.Draw(screen, s, fontSmall, int(x)+uiInt(20), y, t.TextDim)
+		y += uiInt(22)
+	}
+	y += uiInt(14)
+	text.Draw(screen, trf("洗牌后已弃%d张", len(m.discard)), fontSmall, int(x), y, t.Text)
+	y += uiInt(26)
+	var discard []string
+	for _, c := range m.discard {
+		discard = append(discard, tr(c.Text))
+	}
+	for i := 0; i < len(discard); i += 4 {
+		end := i + 4
+		if end > len(discard) {
+			end = len(discard)
+		}
+		text.Draw(screen, strings.Join(discard[i:end], " → "), fontSmall, int(x)+uiInt(20), y, t.TextDim)
+		y += uiInt(22)
+	}
+}
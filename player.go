@@ -3,87 +3,88 @@ package main
 import (
 	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/text"
 	"image/color"
 	"sort"
-	"strconv"
-	"strings"
 )
 
 type playerItem struct {
+	// id 在整局游戏中不变，由玩家的字母和棋子的序号组成，例如"A1"
+	id          string
 	alreadyMove bool
 	step        int
 	pos         point
 	color       color.Color
+	shape       int
+	animPos
 }
 
-func (p *playerItem) Draw() (*ebiten.Image, *ebiten.DrawImageOptions) {
-	if p.alreadyMove {
-		opt := &ebiten.DrawImageOptions{}
-		opt.GeoM.Scale(2, 2)
-		img := ebiten.NewImage(gridLen/2, gridLen/2)
-		img.Fill(color.Alpha{})
-		text.Draw(img, "〇", fontNum, -8, 33, p.color)
-		return img, opt
-	} else {
-		opt := &ebiten.DrawImageOptions{}
-		img := ebiten.NewImage(gridLen, gridLen)
-		img.Fill(color.Alpha{})
-		switch p.step {
-		case 1:
-			text.Draw(img, "①", fontNum, 6, 46, p.color)
-		case 2:
-			text.Draw(img, "②", fontNum, 6, 46, p.color)
-		case 3:
-			text.Draw(img, "③", fontNum, 6, 46, p.color)
-		case 4:
-			text.Draw(img, "④", fontNum, 6, 46, p.color)
-		case 5:
-			text.Draw(img, "⑤", fontNum, 6, 46, p.color)
-		case 6:
-			text.Draw(img, "⑥", fontNum, 6, 46, p.color)
-		}
-		return img, opt
-	}
+func (p *playerItem) Draw(b *board) (*ebiten.Image, *ebiten.DrawImageOptions) {
+	return pieceSprite(p.color, p.shape, p.step, p.next(b.rules), p.alreadyMove, b.pieceSize(p)), &ebiten.DrawImageOptions{}
+}
+
+// next 翻面后下一轮的步数
+func (p *playerItem) next(r *rules) int {
+	return r.FlipSum - p.step
+}
+
+func (p *playerItem) String() string {
+	return p.id
+}
+
+// canMove 这一轮还没有走过，并且还在棋盘上
+func (p *playerItem) canMove(b *board) bool {
+	return !p.alreadyMove && !p.isFinished(b) && !p.isDead(b)
 }
 
 func (p *playerItem) die(b *board) {
-	if b.bigTurn > 7 {
-		p.pos.y = -2
+	logger.WithField("piece", p.id).Info("piece died")
+	playSound(soundDeath)
+	b.addEffect(effectTypeDeath, p.pos)
+	for _, owner := range b.player {
+		if owner.owns(p) {
+			owner.lost++
+		}
+	}
+	if b.rules.PermanentDeath && b.cycle() > 0 {
+		p.pos = b.geo.deadPos()
 	} else {
-		p.pos.y = -1
+		p.pos = b.geo.startPos()
 	}
-	p.pos.x = 0
 }
 
-func (p *playerItem) isDead() bool {
-	return p.pos.x == 0 && p.pos.y == -2
+func (p *playerItem) isDead(b *board) bool {
+	return p.pos == b.geo.deadPos()
 }
 
-func (p *playerItem) isFinished() bool {
-	return p.pos.x == width && p.pos.y == height
+func (p *playerItem) isFinished(b *board) bool {
+	return p.pos == b.geo.finishPos()
 }
 
 func (p *playerItem) tryMove(b *board, d dir) bool {
 	pos := p.pos
 	pos.x += d.x
 	pos.y += d.y
-	if pos.x == width && pos.y == height-1 || pos.x == width-1 && pos.y == height {
-		p.pos.x = width
-		p.pos.y = height
+	if b.geo.isExitGate(pos) {
+		logger.WithField("piece", p.id).Info("piece escaped")
+		b.addEffect(effectTypeExit, p.pos)
+		p.pos = b.geo.finishPos()
 		return true
 	}
-	if pos.outOfRange() || b.monster.pos == pos {
+	if b.geo.outOfRange(pos) || b.monsterAt(pos) != nil {
 		return false
 	}
 	if b.floorShape[pos.y][pos.x] >= floorShapeTypeTransferUp {
 		return false
 	}
-	if b.items[pos.y][pos.x] != nil && !b.items[pos.y][pos.x].tryMove(b, d) {
-		return false
+	if b.items[pos.y][pos.x] != nil {
+		if !b.items[pos.y][pos.x].tryMove(b, d) {
+			return false
+		}
+		playSound(soundPush)
 	}
 	p.pos = pos
 	if b.floorShape[pos.y][pos.x] == floorShapeTypeSlipFloor {
+		playSound(soundSlide)
 		p.tryMove(b, d)
 	}
 	return true
@@ -93,7 +94,7 @@ func (p *playerItem) forceMove(b *board, d dir) {
 	pos := p.pos
 	pos.x += d.x
 	pos.y += d.y
-	if pos.outOfRange() || b.floorShape[pos.y][pos.x] >= floorShapeTypeTransferUp {
+	if b.geo.outOfRange(pos) || b.floorShape[pos.y][pos.x] >= floorShapeTypeTransferUp || b.monsterAt(pos) != nil {
 		p.die(b)
 		return
 	}
@@ -109,12 +110,13 @@ func (p *playerItem) forceMove(b *board, d dir) {
 	}
 	p.pos = pos
 	if b.floorShape[pos.y][pos.x] == floorShapeTypeSlipFloor {
+		playSound(soundSlide)
 		p.tryMove(b, d)
 	}
 }
 
 func (p *playerItem) checkLegal(b *board) bool {
-	if p.pos.x == 0 && p.pos.y == 1 || p.pos.x == width && p.pos.y == height {
+	if p.pos == b.geo.startPos() || p.isFinished(b) {
 		return true
 	}
 	for i := range b.items {
@@ -137,67 +139,124 @@ func (p *playerItem) checkLegal(b *board) bool {
 type player struct {
 	items []*playerItem
 	text  string
+	color color.Color
+	shape int
+	// lost 棋子被怪物吃掉的次数
+	lost int
 }
 
-func (p *player) willMove(num int) *playerItem {
-	for _, item := range p.items {
+// willMove 返回步数为num的能移动的棋子，有好几个时从cur之后的那个开始找，这样连续按同一个数字键可以在它们之间切换
+func (p *player) willMove(b *board, num int, cur *playerItem) *playerItem {
+	item := cur
+	for n := 0; n < len(p.items); n++ {
+		item = p.cycle(b, item, 1)
+		if item == nil {
+			return nil
+		}
 		if item.step == num {
-			if item.alreadyMove || item.isFinished() || item.isDead() {
-				return nil
-			}
 			return item
 		}
 	}
 	return nil
 }
 
-func (p *player) hasItemToMove() bool {
+// cycle 返回cur之后（dir为-1时是之前）下一个能移动的棋子，cur为nil时从头开始
+func (p *player) cycle(b *board, cur *playerItem, dir int) *playerItem {
+	idx := -1
+	if dir < 0 {
+		idx = len(p.items)
+	}
+	for i, item := range p.items {
+		if item == cur {
+			idx = i
+		}
+	}
+	for n := 0; n < len(p.items); n++ {
+		idx = (idx + dir + len(p.items)) % len(p.items)
+		item := p.items[idx]
+		if item.canMove(b) {
+			return item
+		}
+	}
+	return nil
+}
+
+func (p *player) owns(item *playerItem) bool {
+	for _, it := range p.items {
+		if it == item {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *player) hasItemToMove(b *board) bool {
 	for _, item := range p.items {
-		if !item.alreadyMove && !item.isFinished() && !item.isDead() {
+		if item.canMove(b) {
 			return true
 		}
 	}
 	return false
 }
 
-func (p *player) nextTurn() {
+func (p *player) nextTurn(b *board) {
 	for _, item := range p.items {
 		item.alreadyMove = false
-		item.step = 7 - item.step
+		item.step = item.next(b.rules)
 	}
 }
 
-func newPlayer(c color.Color, text string) *player {
-	return &player{
-		text: text,
-		items: []*playerItem{
-			{step: 1, pos: point{0, -1}, color: c},
-			{step: 3, pos: point{0, -1}, color: c},
-			{step: 4, pos: point{0, -1}, color: c},
-			{step: 5, pos: point{0, -1}, color: c},
-		},
+const maxPlayers = 6
+
+var playerNames = []string{"红方", "绿方", "黄方", "蓝方", "紫方", "橙方"}
+
+// newPlayer 第i个玩家，决定了棋子的颜色和形状
+func newPlayer(b *board, i int, text string) *player {
+	start := b.geo.startPos()
+	c := playerColor(i)
+	p := &player{
+		text:  text,
+		color: c,
+		shape: i,
 	}
+	for n, step := range b.rules.pieceSet(len(b.player)) {
+		p.items = append(p.items, &playerItem{
+			id:    fmt.Sprintf("%c%d", 'A'+i, n+1),
+			step:  step,
+			pos:   start,
+			color: c,
+			shape: i,
+		})
+	}
+	return p
 }
 
-func (p *player) display(b *board) {
-	s := fmt.Sprintf("剩余%d张牌，", len(b.monster.deck))
-	if b.monster.lastCard != nil {
-		s = "怪物的上一张牌是" + b.monster.lastCard.text + s
-	}
-	var canMoveItems []int
+// name 返回带形状标记的玩家名字
+func (p *player) name() string {
+	return playerShapes[p.shape] + p.text
+}
+
+// canMoveItems 返回能移动的棋子，按步数排序
+func (p *player) canMoveItems(b *board) []*playerItem {
+	var items []*playerItem
 	for _, item := range p.items {
-		if !item.alreadyMove && !item.isFinished() && !item.isDead() {
-			canMoveItems = append(canMoveItems, item.step)
+		if item.canMove(b) {
+			items = append(items, item)
 		}
 	}
-	sort.Ints(canMoveItems)
-	s += "轮到" + p.text
-	if canMoveItems != nil {
-		var canMoveItemsString []string
-		for _, item := range canMoveItems {
-			canMoveItemsString = append(canMoveItemsString, strconv.Itoa(item))
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].step < items[j].step
+	})
+	return items
+}
+
+func (p *player) countFinished(b *board) (finished, dead int) {
+	for _, item := range p.items {
+		if item.isFinished(b) {
+			finished++
+		} else if item.isDead(b) {
+			dead++
 		}
-		s += "，能移动的棋子有" + strings.Join(canMoveItemsString, "，")
 	}
-	ebiten.SetWindowTitle(s)
+	return
 }
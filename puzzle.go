@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"os"
+	"strings"
+	"time"
+)
+
+const puzzleSolutionsFile = "puzzle_solutions.json"
+
+// puzzle 残局：固定的布局、棋子位置、怪物的位置和朝向，以及怪物这一轮会抽到的牌。
+// 玩家走完这一轮所有的棋子，怪物移动一次后检查是否达成目标
+type puzzle struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Layout      layout `json:"layout"`
+	// Pieces 每个玩家的棋子，棋子编号和正常游戏一样是A1、A2、B1……
+	Pieces [][]puzzlePiece `json:"pieces"`
+	Card   card            `json:"card"`
+	Goal   puzzleGoal      `json:"goal"`
+}
+
+type puzzlePiece struct {
+	Step int   `json:"step"`
+	Pos  point `json:"pos"`
+	// At 为start、finish或dead时棋子在托盘里，忽略Pos
+	At    string `json:"at,omitempty"`
+	Moved bool   `json:"moved,omitempty"`
+}
+
+type puzzleGoal struct {
+	// Escape 这些棋子必须逃出
+	Escape []string `json:"escape,omitempty"`
+	// MinEscaped 至少要逃出几个棋子，包括开局时已经逃出的
+	MinEscaped int `json:"min_escaped,omitempty"`
+	// NoDeaths 不能有棋子被怪物吃掉
+	NoDeaths bool `json:"no_deaths,omitempty"`
+}
+
+// puzzleSolution 记录每个残局最好的解法，逃出的棋子多的更好，一样多时被吃掉的少的更好
+type puzzleSolution struct {
+	Moves   []string  `json:"moves"`
+	Escaped int       `json:"escaped"`
+	Lost    int       `json:"lost"`
+	Time    time.Time `json:"time"`
+}
+
+// loadPuzzle 读取残局文件，布局中没有geometry时使用geo
+func loadPuzzle(file string, geo *geometry) (*puzzle, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p := &puzzle{}
+	if err = json.Unmarshal(buf, p); err != nil {
+		return nil, err
+	}
+	if p.Layout.Geometry.Width == 0 {
+		p.Layout.Geometry = *geo
+	}
+	p.Layout.Geometry.init()
+	if err = p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *puzzle) validate() error {
+	if p.Name == "" {
+		return errors.New(tr("残局没有名字"))
+	}
+	if err := p.Layout.validate(); err != nil {
+		return err
+	}
+	if len(p.Pieces) < 1 || len(p.Pieces) > maxPlayers {
+		return errors.New(tr("残局的玩家数不合法"))
+	}
+	if p.Card.Step < 1 || p.Card.Kills < 1 {
+		return errors.New(tr("残局的怪物牌不合法"))
+	}
+	b := newEmptyBoard(&p.Layout.Geometry)
+	if err := b.applyLayout(&p.Layout); err != nil {
+		return err
+	}
+	used := make(map[point]bool)
+	for _, pieces := range p.Pieces {
+		if len(pieces) < 1 || len(pieces) > 6 {
+			return errors.New(tr("每个玩家要有1-6个棋子"))
+		}
+		for _, piece := range pieces {
+			if piece.Step < 1 || piece.Step > 20 {
+				return fmt.Errorf(tr("棋子步数%d不合法"), piece.Step)
+			}
+			switch piece.At {
+			case "start", "finish", "dead":
+				continue
+			case "":
+			default:
+				return fmt.Errorf(tr("棋子位置%s不合法"), piece.At)
+			}
+			pos := piece.Pos
+			if b.geo.outOfRange(pos) || b.items[pos.y][pos.x] != nil || b.floorShape[pos.y][pos.x] != floorShapeTypeEmpty || b.monsterAt(pos) != nil {
+				return fmt.Errorf(tr("棋子不能放在%v"), pos)
+			}
+			if used[pos] {
+				return fmt.Errorf(tr("%v有两个棋子"), pos)
+			}
+			used[pos] = true
+		}
+	}
+	return nil
+}
+
+// newPuzzleBoard 按残局摆好棋盘，怪物第一张牌是残局指定的牌。残局从第二轮开始，不受第一轮的规则限制
+func newPuzzleBoard(p *puzzle) *board {
+	b := newEmptyBoard(&p.Layout.Geometry)
+	b.puzzle = p
+	if err := b.applyLayout(&p.Layout); err != nil {
+		logger.Fatal(err)
+	}
+	c := p.Card
+	b.monsters[0].deck = append([]*card{&c}, newDeck(b.random, b.rules.Deck)...)
+	b.player = make([]*player, len(p.Pieces))
+	for i, pieces := range p.Pieces {
+		pl := &player{text: tr(playerNames[i]), color: playerColor(i), shape: i}
+		for n, piece := range pieces {
+			item := &playerItem{
+				id:          fmt.Sprintf("%c%d", 'A'+i, n+1),
+				step:        piece.Step,
+				pos:         piece.Pos,
+				color:       pl.color,
+				shape:       i,
+				alreadyMove: piece.Moved,
+			}
+			switch piece.At {
+			case "start":
+				item.pos = b.geo.startPos()
+			case "finish":
+				item.pos = b.geo.finishPos()
+			case "dead":
+				item.pos = b.geo.deadPos()
+			}
+			item.teleport(item.pos)
+			pl.items = append(pl.items, item)
+		}
+		b.player[i] = pl
+	}
+	b.bigTurn = 1
+	if !b.player[0].hasItemToMove(b) {
+		b.nextPlayer()
+	}
+	return b
+}
+
+// goals 目标的文字说明
+func (g *puzzleGoal) goals() []string {
+	var goals []string
+	if len(g.Escape) > 0 {
+		goals = append(goals, trf("让%s逃出", strings.Join(g.Escape, ",")))
+	}
+	if g.MinEscaped > 0 {
+		goals = append(goals, trf("至少逃出%d个棋子", g.MinEscaped))
+	}
+	if g.NoDeaths {
+		goals = append(goals, tr("不能有棋子被吃掉"))
+	}
+	return goals
+}
+
+// check 返回没有达成的目标，全部达成时返回nil
+func (g *puzzleGoal) check(b *board) []string {
+	var failed []string
+	finished := make(map[string]bool)
+	escaped, lost := 0, 0
+	for _, p := range b.player {
+		for _, item := range p.items {
+			if item.isFinished(b) {
+				finished[item.id] = true
+				escaped++
+			}
+		}
+		lost += p.lost
+	}
+	for _, id := range g.Escape {
+		if !finished[id] {
+			failed = append(failed, trf("%s没有逃出", id))
+		}
+	}
+	if escaped < g.MinEscaped {
+		failed = append(failed, trf("只逃出了%d个棋子", escaped))
+	}
+	if g.NoDeaths && lost > 0 {
+		failed = append(failed, trf("有%d个棋子被吃掉了", lost))
+	}
+	return failed
+}
+
+// checkPuzzle 怪物走完后检查残局的目标，成功时记录解法
+func (b *board) checkPuzzle() {
+	if b.puzzle == nil || b.puzzleDone || b.bigTurn < 2 || b.monsterBusy() {
+		return
+	}
+	b.puzzleDone = true
+	b.puzzleFailed = b.puzzle.Goal.check(b)
+	logger.WithField("puzzle", b.puzzle.Name).WithField("failed", b.puzzleFailed).WithField("moves", b.moves).Info("puzzle finished")
+	solutions := loadPuzzleSolutions()
+	if len(b.puzzleFailed) == 0 {
+		s := &puzzleSolution{Moves: b.moves, Time: time.Now()}
+		for _, p := range b.player {
+			finished, _ := p.countFinished(b)
+			s.Escaped += finished
+			s.Lost += p.lost
+		}
+		if best := solutions[b.puzzle.Name]; best == nil || s.Escaped > best.Escaped || s.Escaped == best.Escaped && s.Lost < best.Lost {
+			solutions[b.puzzle.Name] = s
+			savePuzzleSolutions(solutions)
+		}
+	}
+	b.bestSolution = solutions[b.puzzle.Name]
+}
+
+func loadPuzzleSolutions() map[string]*puzzleSolution {
+	solutions := make(map[string]*puzzleSolution)
+	buf, err := os.ReadFile(puzzleSolutionsFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.WithError(err).Warn("read puzzle solutions failed")
+		}
+		return solutions
+	}
+	if err = json.Unmarshal(buf, &solutions); err != nil {
+		logger.WithError(err).Warn("parse puzzle solutions failed")
+	}
+	return solutions
+}
+
+func savePuzzleSolutions(solutions map[string]*puzzleSolution) {
+	buf, err := json.MarshalIndent(solutions, "", "  ")
+	if err != nil {
+		logger.WithError(err).Error("marshal puzzle solutions failed")
+		return
+	}
+	if err = os.WriteFile(puzzleSolutionsFile, buf, 0644); err != nil {
+		logger.WithError(err).Error("save puzzle solutions failed")
+	}
+}
+
+// stepPicked 选中的棋子向d走一步，成功时记下方向。推石头和滑过血池的结果和走的路线有关，所以解法要记录每一步
+func (b *board) stepPicked(d dir) bool {
+	if !b.pickedPlayerItem.tryMove(b, d) {
+		return false
+	}
+	b.path += dirArrows[d]
+	return true
+}
+
+var dirArrows = map[dir]string{up: "↑", down: "↓", left: "←", right: "→"}
+
+// drawPuzzle 残局结束后显示目标、结果和最好的解法
+func (b *board) drawPuzzle(screen *ebiten.Image) {
+	if !b.puzzleDone {
+		return
+	}
+	t := theView.theme
+	p := b.puzzle
+	lines := []textPart{{tr("残局：") + p.Name, t.Text}}
+	if p.Description != "" {
+		lines = append(lines, textPart{p.Description, t.TextDim})
+	}
+	for _, goal := range p.Goal.goals() {
+		lines = append(lines, textPart{tr("目标：") + goal, t.Text})
+	}
+	if len(b.puzzleFailed) == 0 {
+		lines = append(lines, textPart{tr("成功！"), t.Exit})
+	} else {
+		lines = append(lines, textPart{tr("失败：") + strings.Join(b.puzzleFailed, "，"), t.Death})
+	}
+	if s := b.bestSolution; s != nil {
+		lines = append(lines, textPart{tr("最好的解法：") + strings.Join(s.Moves, " "), t.Text})
+	}
+	lines = append(lines, textPart{tr("按Enter键重来"), t.TextDim})
+	drawTextBox(screen, uiInt(60), uiInt(120), lines)
+}
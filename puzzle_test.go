@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPuzzleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		pieces  [][]puzzlePiece
+		wantErr bool
+	}{
+		{"正常", [][]puzzlePiece{{{Step: 3, Pos: point{3, 5}}, {Step: 1, Pos: point{4, 8}}}}, false},
+		{"同一个玩家的两个棋子在同一格", [][]puzzlePiece{{{Step: 3, Pos: point{3, 5}}, {Step: 1, Pos: point{3, 5}}}}, true},
+		{"两个玩家的棋子在同一格", [][]puzzlePiece{{{Step: 3, Pos: point{3, 5}}}, {{Step: 1, Pos: point{3, 5}}}}, true},
+		{"棋子在石头上", [][]puzzlePiece{{{Step: 3, Pos: point{4, 5}}}}, true},
+		{"托盘里可以有几个棋子", [][]puzzlePiece{{{Step: 3, At: "start"}, {Step: 1, At: "start"}}, {{Step: 2, At: "finish"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tutorialPuzzle()
+			p.Pieces = tt.pieces
+			if err := p.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("错误是%v", err)
+			}
+		})
+	}
+}
+
+func TestPuzzleMovesRecordPath(t *testing.T) {
+	b := newPuzzleBoard(tutorialPuzzle())
+	b.pickedPlayerItem = b.player[0].items[0]
+	for _, d := range []dir{right, right, right, down} {
+		b.stepPicked(d)
+	}
+	// A1把石头向右推了三格，再向下走一步
+	if b.path != "→→→↓" || b.pickedPlayerItem.pos != (point{6, 6}) || b.items[5][7] == nil {
+		t.Fatalf("走了%s，到了%v", b.path, b.pickedPlayerItem.pos)
+	}
+}
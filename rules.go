@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type rules struct {
+	Name string `json:"name"`
+	// FlipSum 每轮结束时棋子翻面，新的步数为FlipSum减去原步数
+	FlipSum int `json:"flip_sum"`
+	// FirstRoundExcludeStep 第一轮怪物不会抽到步数大于等于这个值的牌，为0表示不排除
+	FirstRoundExcludeStep int `json:"first_round_exclude_step"`
+	// FirstDraw 第一轮最上面的牌被排除时的处理方法
+	FirstDraw firstDrawRule `json:"first_draw"`
+	// ReshuffleAt 牌堆剩下这么多张牌时，把剩下的牌和弃牌一起重新洗牌
+	ReshuffleAt int `json:"reshuffle_at"`
+	// PermanentDeath 怪物牌堆用完一次后进入第二阶段，这时死亡的棋子不能再回到起点
+	PermanentDeath bool `json:"permanent_death"`
+	StoneCount     int  `json:"stone_count"`
+	// PieceSets 不同人数时每个玩家的棋子步数，没写的人数使用defaultPieceSets
+	PieceSets map[int][]int `json:"piece_sets,omitempty"`
+	Deck      []*card       `json:"deck"`
+}
+
+type firstDrawRule string
+
+const (
+	// firstDrawReshuffle 重新洗牌直到最上面的牌可以用
+	firstDrawReshuffle firstDrawRule = "reshuffle"
+	// firstDrawBury 把被排除的牌都放到牌堆底下
+	firstDrawBury firstDrawRule = "bury"
+)
+
+// defaultPieceSets 1-4人和原来一样每人四个棋子，5-6人棋盘太挤，每人只有三个
+func defaultPieceSets() map[int][]int {
+	return map[int][]int{
+		1: {1, 3, 4, 5},
+		2: {1, 3, 4, 5},
+		3: {1, 3, 4, 5},
+		4: {1, 3, 4, 5},
+		5: {2, 3, 5},
+		6: {2, 3, 5},
+	}
+}
+
+func (r *rules) pieceSet(playerNum int) []int {
+	if set, ok := r.PieceSets[playerNum]; ok {
+		return set
+	}
+	return defaultPieceSets()[playerNum]
+}
+
+func rulesPresets() []*rules {
+	return []*rules{
+		{Name: "基础", FlipSum: 7, FirstRoundExcludeStep: 20, FirstDraw: firstDrawReshuffle, PermanentDeath: true, StoneCount: 11, Deck: defaultDeck()},
+		{Name: "进阶", FlipSum: 7, FirstRoundExcludeStep: 0, FirstDraw: firstDrawReshuffle, PermanentDeath: true, StoneCount: 13, Deck: append(defaultDeck(),
+			&card{"转身7", 7, 99, cardEffectTurn},
+			&card{"跳跃5", 5, 99, cardEffectJump},
+		)},
+		{Name: "儿童", FlipSum: 7, FirstRoundExcludeStep: 10, FirstDraw: firstDrawBury, PermanentDeath: false, StoneCount: 6, Deck: []*card{
+			{"4", 4, 99, cardEffectNone},
+			{"5", 5, 99, cardEffectNone},
+			{"5", 5, 99, cardEffectNone},
+			{"6", 6, 99, cardEffectNone},
+			{"6", 6, 99, cardEffectNone},
+			{"7", 7, 99, cardEffectNone},
+			{"10", 10, 1, cardEffectNone},
+		}},
+	}
+}
+
+func loadRules(file string) (*rules, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	r := rulesPresets()[0]
+	r.Name = file
+	if err = json.Unmarshal(buf, r); err != nil {
+		return nil, err
+	}
+	if r.FlipSum < 2 || r.StoneCount < 0 || r.ReshuffleAt < 0 || r.ReshuffleAt >= len(r.Deck) {
+		return nil, fmt.Errorf("invalid rules: %s", file)
+	}
+	if r.FirstDraw != firstDrawReshuffle && r.FirstDraw != firstDrawBury {
+		return nil, fmt.Errorf("invalid first_draw: %s", r.FirstDraw)
+	}
+	for n := range r.PieceSets {
+		if n < 1 || n > maxPlayers {
+			return nil, fmt.Errorf("invalid piece set for %d players", n)
+		}
+	}
+	// 没有指定的人数用默认的棋子，默认的棋子也要符合FlipSum
+	for n := 1; n <= maxPlayers; n++ {
+		set := r.pieceSet(n)
+		if len(set) == 0 || len(set) > 9 {
+			return nil, fmt.Errorf("invalid piece set for %d players", n)
+		}
+		for _, step := range set {
+			if step < 1 || step >= r.FlipSum {
+				return nil, fmt.Errorf("invalid piece step %d for %d players", step, n)
+			}
+		}
+	}
+	if err = validateDeck(r.Deck); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRulesPieceSets(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"默认", `{}`, false},
+		{"指定的棋子", `{"piece_sets": {"2": [1, 2, 3]}}`, false},
+		{"指定的棋子步数为0", `{"piece_sets": {"2": [0, 2, 3]}}`, true},
+		{"指定的棋子步数太大", `{"piece_sets": {"2": [1, 2, 7]}}`, true},
+		{"人数不对", `{"piece_sets": {"7": [1, 2, 3]}}`, true},
+		{"没有棋子", `{"piece_sets": {"3": []}}`, true},
+		{"默认的棋子步数太大", `{"flip_sum": 5}`, true},
+		{"指定所有人数后可以用小的FlipSum", `{"flip_sum": 5, "piece_sets": {"1": [1, 4], "2": [1, 4], "3": [2, 3], "4": [2, 3], "5": [1], "6": [1]}}`, false},
+		{"只指定一部分人数时默认的也要检查", `{"flip_sum": 5, "piece_sets": {"1": [1, 4], "2": [1, 4]}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "rules.json")
+			if err := os.WriteFile(file, []byte(tt.json), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadRules(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误是%v", err)
+			}
+		})
+	}
+}
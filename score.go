@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	highScoreFile = "highscores.json"
+	maxHighScores = 10
+	// dailyKeepDays 每日挑战的成绩只保留最近几天的
+	dailyKeepDays = 7
+)
+
+type gameMode int
+
+const (
+	modeNormal gameMode = iota
+	modeSolo
+	modeDaily
+	modeTutorial
+)
+
+var modeNames = []string{"多人", "单人挑战", "每日挑战", "教程"}
+
+// soloScore 单人挑战的一局成绩，逃出的棋子加分，用掉的轮数和被吃掉的次数扣分
+type soloScore struct {
+	Escaped int    `json:"escaped"`
+	Rounds  int    `json:"rounds"`
+	Lost    int    `json:"lost"`
+	Score   int    `json:"score"`
+	Rules   string `json:"rules"`
+	// Brain和Monsters 怪物AI和怪物数量，和Rules一起决定这局成绩在哪个排行榜
+	Brain    string    `json:"brain"`
+	Monsters int       `json:"monsters"`
+	Daily    string    `json:"daily,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+type highScores struct {
+	Solo  []*soloScore `json:"solo"`
+	Daily []*soloScore `json:"daily"`
+}
+
+func scoreOf(escaped, rounds, lost int) int {
+	return escaped*100 - rounds*10 - lost*30
+}
+
+// dailyDate 今天的日期，每日挑战用它作为随机数种子，所以同一天所有人的布局和牌堆都一样
+func dailyDate() string {
+	return time.Now().Format("20060102")
+}
+
+func dailySeed(date string) int64 {
+	seed, err := strconv.ParseInt(date, 10, 64)
+	if err != nil {
+		logger.WithError(err).Error("invalid daily date")
+	}
+	return seed
+}
+
+func loadHighScores() *highScores {
+	h := &highScores{}
+	buf, err := os.ReadFile(highScoreFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.WithError(err).Warn("read high scores failed")
+		}
+		return h
+	}
+	if err = json.Unmarshal(buf, h); err != nil {
+		logger.WithError(err).Warn("parse high scores failed")
+	}
+	return h
+}
+
+func (h *highScores) save() {
+	buf, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		logger.WithError(err).Error("marshal high scores failed")
+		return
+	}
+	if err = os.WriteFile(highScoreFile, buf, 0644); err != nil {
+		logger.WithError(err).Error("save high scores failed")
+	}
+}
+
+// sameTable 两局成绩是否在同一个排行榜里：每日挑战按日期分开，单人挑战按规则、怪物AI和怪物数量分开
+func (s *soloScore) sameTable(other *soloScore) bool {
+	if s.Daily != "" || other.Daily != "" {
+		return s.Daily == other.Daily
+	}
+	return s.Rules == other.Rules && s.Brain == other.Brain && s.Monsters == other.Monsters
+}
+
+// add 记录一局成绩，返回它在排行榜中的名次（从1开始），没有进入排行榜时返回0
+func (h *highScores) add(s *soloScore) int {
+	if s.Daily == "" {
+		h.Solo = append(h.Solo, s)
+		sortScores(h.Solo)
+		var kept []*soloScore
+		for _, old := range h.Solo {
+			if len(filterScores(kept, old)) < maxHighScores {
+				kept = append(kept, old)
+			}
+		}
+		h.Solo = kept
+		return rankOf(h.table(s), s)
+	}
+	oldest := time.Now().AddDate(0, 0, -dailyKeepDays).Format("20060102")
+	var kept []*soloScore
+	for _, old := range h.Daily {
+		if old.Daily > oldest {
+			kept = append(kept, old)
+		}
+	}
+	h.Daily = append(kept, s)
+	sortScores(h.Daily)
+	return rankOf(h.table(s), s)
+}
+
+// table 和key在同一个排行榜里的前maxHighScores名
+func (h *highScores) table(key *soloScore) []*soloScore {
+	scores := filterScores(h.Solo, key)
+	if key.Daily != "" {
+		scores = filterScores(h.Daily, key)
+	}
+	if len(scores) > maxHighScores {
+		scores = scores[:maxHighScores]
+	}
+	return scores
+}
+
+func filterScores(scores []*soloScore, key *soloScore) []*soloScore {
+	var filtered []*soloScore
+	for _, s := range scores {
+		if s.sameTable(key) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+func sortScores(scores []*soloScore) {
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+}
+
+func rankOf(scores []*soloScore, s *soloScore) int {
+	for i, old := range scores {
+		if old == s {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// recordScore 单人模式游戏结束时记录一次成绩
+func (b *board) recordScore() {
+	if b.mode == modeNormal || b.score != nil {
+		return
+	}
+	p := b.player[0]
+	escaped, _ := p.countFinished(b)
+	b.score = &soloScore{
+		Escaped:  escaped,
+		Rounds:   b.bigTurn,
+		Lost:     p.lost,
+		Score:    scoreOf(escaped, b.bigTurn, p.lost),
+		Rules:    b.rules.Name,
+		Brain:    b.monsters[0].brain.Name(),
+		Monsters: len(b.monsters),
+		Daily:    b.daily,
+		Time:     time.Now(),
+	}
+	h := loadHighScores()
+	b.scoreRank = h.add(b.score)
+	b.scoreTable = h.table(b.score)
+	h.save()
+	logger.WithField("score", b.score.Score).WithField("rank", b.scoreRank).WithField("daily", b.daily).Info("solo game finished")
+}
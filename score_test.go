@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHighScoresTables(t *testing.T) {
+	h := &highScores{}
+	basic := &soloScore{Rules: "基础", Brain: "经典", Monsters: 1}
+	kids := &soloScore{Rules: "儿童", Brain: "随机", Monsters: 1}
+	three := &soloScore{Rules: "基础", Brain: "经典", Monsters: 3}
+	for i := 0; i < maxHighScores+2; i++ {
+		s := *basic
+		s.Score = 100 + i
+		h.add(&s)
+	}
+	if n := len(h.table(basic)); n != maxHighScores {
+		t.Fatalf("基础规则的排行榜有%d个成绩", n)
+	}
+	kids.Score = 50
+	if rank := h.add(kids); rank != 1 {
+		t.Fatalf("儿童规则的第一个成绩排第%d名", rank)
+	}
+	three.Score = 10
+	if rank := h.add(three); rank != 1 {
+		t.Fatalf("三个怪物的第一个成绩排第%d名", rank)
+	}
+	low := *basic
+	low.Score = 0
+	if rank := h.add(&low); rank != 0 {
+		t.Fatalf("分数最低的成绩排第%d名", rank)
+	}
+	if n := len(h.table(basic)); n != maxHighScores {
+		t.Fatalf("基础规则的排行榜有%d个成绩", n)
+	}
+	if len(h.Solo) != maxHighScores+2 {
+		t.Fatalf("一共保存了%d个成绩", len(h.Solo))
+	}
+	daily := &soloScore{Rules: "基础", Brain: "经典", Monsters: 1, Daily: dailyDate(), Score: 1}
+	if rank := h.add(daily); rank != 1 || len(h.table(daily)) != 1 {
+		t.Fatalf("每日挑战的成绩排第%d名", rank)
+	}
+}
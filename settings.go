@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+const settingsFile = "settings.json"
+
+type settings struct {
+	Animation   bool    `json:"animation"`
+	Mute        bool    `json:"mute"`
+	SoundVolume float64 `json:"sound_volume"`
+	MusicVolume float64 `json:"music_volume"`
+	Theme       string  `json:"theme"`
+	Language    string  `json:"language"`
+	// Colorblind 使用色盲友好的玩家颜色
+	Colorblind   bool        `json:"colorblind"`
+	HighContrast bool        `json:"high_contrast"`
+	Keys         keyBindings `json:"keys"`
+}
+
+var gameSettings = &settings{
+	Animation:   true,
+	SoundVolume: 0.8,
+	MusicVolume: 0.5,
+	Keys:        defaultKeyBindings(),
+}
+
+func loadSettings() {
+	buf, err := os.ReadFile(settingsFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.WithError(err).Warn("read settings failed")
+		}
+		return
+	}
+	if err = json.Unmarshal(buf, gameSettings); err != nil {
+		logger.WithError(err).Warn("parse settings failed")
+	}
+}
+
+func (s *settings) save() {
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		logger.WithError(err).Error("marshal settings failed")
+		return
+	}
+	if err = os.WriteFile(settingsFile, buf, 0644); err != nil {
+		logger.WithError(err).Error("save settings failed")
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"math"
+	"strconv"
+)
+
+const (
+	setupRowMode = iota
+	setupRowPlayerNum
+	setupRowRules
+	setupRowBrain
+	setupRowMonsterNum
+	setupRowSharedDeck
+	setupRowAnimation
+	setupRowSoundVolume
+	setupRowMusicVolume
+	setupRowMute
+	setupRowTheme
+	setupRowLanguage
+	setupRowColorblind
+	setupRowHighContrast
+	setupRowCount
+)
+
+type gameOptions struct {
+	playerNum int
+	rules     *rules
+	geo       *geometry
+	layout    *layout
+	brain     monsterBrain
+	mode      gameMode
+	// monsterNum 棋盘上有几个怪物，sharedDeck为true时所有怪物共用一副牌
+	monsterNum int
+	sharedDeck bool
+}
+
+type setup struct {
+	game    ebiten.Game
+	options *gameOptions
+	presets []*rules
+	preset  int
+	brain   int
+	row     int
+	scores  *highScores
+}
+
+func newSetup(options *gameOptions, presets []*rules) *setup {
+	ebiten.SetWindowTitle("Fearsome Floors")
+	return &setup{options: options, presets: presets, scores: loadHighScores()}
+}
+
+func (s *setup) Update() error {
+	if t, ok := s.game.(*tutorial); ok && t.finished {
+		// 教程结束后回到设置界面
+		s.game = nil
+		return nil
+	}
+	if s.game != nil {
+		return s.game.Update()
+	}
+	delta := 0
+	switch {
+	case isJustPressed(actionUp):
+		s.row = (s.row + setupRowCount - 1) % setupRowCount
+	case isJustPressed(actionDown):
+		s.row = (s.row + 1) % setupRowCount
+	case isJustPressed(actionLeft):
+		delta = -1
+	case isJustPressed(actionRight):
+		delta = 1
+	case isJustPressed(actionConfirm):
+		s.options.rules = s.presets[s.preset]
+		s.options.brain = monsterBrains[s.brain]
+		if s.options.mode != modeNormal {
+			s.options.playerNum = 1
+		}
+		if s.options.mode == modeDaily {
+			// 每日挑战固定用默认规则和一个经典怪物，保证同一天大家玩的是同一局
+			s.options.rules = s.presets[0]
+			s.options.brain = monsterBrains[0]
+			s.options.monsterNum = 1
+		}
+		if s.options.mode == modeTutorial {
+			s.game = newTutorial()
+			return nil
+		}
+		s.game = newBoard(s.options)
+	}
+	if delta != 0 {
+		switch s.row {
+		case setupRowMode:
+			s.options.mode = (s.options.mode + gameMode(delta) + gameMode(len(modeNames))) % gameMode(len(modeNames))
+		case setupRowPlayerNum:
+			s.options.playerNum = (s.options.playerNum+delta+maxPlayers-1)%maxPlayers + 1
+		case setupRowRules:
+			s.preset = (s.preset + delta + len(s.presets)) % len(s.presets)
+		case setupRowBrain:
+			s.brain = (s.brain + delta + len(monsterBrains)) % len(monsterBrains)
+		case setupRowMonsterNum:
+			s.options.monsterNum = (s.options.monsterNum+delta+maxMonsters-1)%maxMonsters + 1
+		case setupRowSharedDeck:
+			s.options.sharedDeck = !s.options.sharedDeck
+		case setupRowAnimation:
+			gameSettings.Animation = !gameSettings.Animation
+		case setupRowSoundVolume:
+			gameSettings.SoundVolume = clampVolume(gameSettings.SoundVolume + 0.1*float64(delta))
+			updateVolume()
+			playSound(soundStep)
+		case setupRowMusicVolume:
+			gameSettings.MusicVolume = clampVolume(gameSettings.MusicVolume + 0.1*float64(delta))
+		case setupRowMute:
+			gameSettings.Mute = !gameSettings.Mute
+		case setupRowTheme:
+			idx := 0
+			for i, t := range themes {
+				if t == currentTheme() {
+					idx = i
+				}
+			}
+			gameSettings.Theme = themes[(idx+delta+len(themes))%len(themes)].Name
+		case setupRowLanguage:
+			idx := 0
+			for i, l := range locales {
+				if l == currentLocale() {
+					idx = i
+				}
+			}
+			gameSettings.Language = locales[(idx+delta+len(locales))%len(locales)].Code
+		case setupRowColorblind:
+			gameSettings.Colorblind = !gameSettings.Colorblind
+		case setupRowHighContrast:
+			gameSettings.HighContrast = !gameSettings.HighContrast
+		}
+		if s.row > setupRowSharedDeck {
+			gameSettings.save()
+			updateVolume()
+		}
+	}
+	return nil
+}
+
+func (s *setup) Draw(screen *ebiten.Image) {
+	if s.game != nil {
+		s.game.Draw(screen)
+		return
+	}
+	t := theView.theme
+	screen.Fill(t.Background)
+	r := s.presets[s.preset]
+	playerNum := strconv.Itoa(s.options.playerNum)
+	if s.options.mode != modeNormal {
+		playerNum = "1"
+	}
+	lines := []string{
+		tr("模式：") + tr(modeNames[s.options.mode]),
+		tr("人数：") + playerNum,
+		tr("规则：") + tr(r.Name),
+		tr("怪物AI：") + tr(monsterBrains[s.brain].Name()),
+		tr("怪物数量：") + strconv.Itoa(s.options.monsterNum),
+		tr("多个怪物的牌堆：") + sharedText(s.options.sharedDeck),
+		tr("动画：") + onOff(gameSettings.Animation),
+		trf("音效音量：%.0f%%", gameSettings.SoundVolume*100),
+		trf("音乐音量：%.0f%%", gameSettings.MusicVolume*100),
+		tr("静音：") + onOff(gameSettings.Mute),
+		tr("主题：") + tr(t.Name),
+		tr("语言：") + currentLocale().Name,
+		tr("色盲配色：") + onOff(gameSettings.Colorblind),
+		tr("高对比度：") + onOff(gameSettings.HighContrast),
+	}
+	for i, line := range lines {
+		if i == s.row {
+			line = "> " + line
+		}
+		text.Draw(screen, line, fontAlpha, uiInt(100), uiInt(110+36*i), t.Text)
+	}
+	permanentDeath := tr("永不")
+	if r.PermanentDeath {
+		permanentDeath = tr("第二阶段（第二副牌）")
+	}
+	firstRound := tr("无限制")
+	if r.FirstRoundExcludeStep > 0 {
+		firstRound = trf("不抽%d步及以上的牌", r.FirstRoundExcludeStep)
+		if r.FirstDraw == firstDrawBury {
+			firstRound += tr("（放到牌底）")
+		} else {
+			firstRound += tr("（重新洗牌）")
+		}
+	}
+	details := []string{
+		trf("棋子翻面：%d减去当前步数", r.FlipSum),
+		tr("怪物第一轮：") + firstRound,
+		tr("棋子永久死亡：") + permanentDeath,
+		trf("石头数量：%d", r.StoneCount),
+		tr("每人的棋子：") + fmt.Sprint(r.pieceSet(s.options.playerNum)),
+		tr("怪物牌堆：") + deckSummary(r.Deck),
+		trf("重新洗牌：牌堆剩%d张时", r.ReshuffleAt),
+	}
+	switch s.options.mode {
+	case modeTutorial:
+		details = []string{tr("教程：一步一步学会移动棋子、推石头、"), tr("血池和怪物转向的规则")}
+	case modeSolo, modeDaily:
+		key := &soloScore{Rules: s.presets[s.preset].Name, Brain: monsterBrains[s.brain].Name(), Monsters: s.options.monsterNum}
+		if s.options.mode == modeDaily {
+			key.Daily = dailyDate()
+			details = append(details, tr("每日挑战：默认棋盘和规则、一个经典怪物"))
+		}
+		best := tr("无")
+		if scores := s.scores.table(key); len(scores) > 0 {
+			best = strconv.Itoa(scores[0].Score)
+		}
+		details = append(details, trf("得分：逃出%d分，每轮%d分，被吃%d分", scoreOf(1, 0, 0), scoreOf(0, 1, 0), scoreOf(0, 0, 1)), tr("最高分：")+best)
+	}
+	for i, line := range details {
+		text.Draw(screen, line, fontAlpha, uiInt(640), uiInt(110+36*i), t.TextDim)
+	}
+	text.Draw(screen, tr("上下键选择，左右键修改，Enter键开始游戏"), fontAlpha, uiInt(100), uiInt(650), t.Text)
+}
+
+func (s *setup) Layout(outsideWidth, outsideHeight int) (int, int) {
+	if s.game != nil {
+		return s.game.Layout(outsideWidth, outsideHeight)
+	}
+	return layoutView(outsideWidth, outsideHeight)
+}
+
+func sharedText(shared bool) string {
+	if shared {
+		return tr("共用")
+	}
+	return tr("各自")
+}
+
+func onOff(b bool) string {
+	if b {
+		return tr("开")
+	}
+	return tr("关")
+}
+
+func clampVolume(v float64) float64 {
+	return math.Round(math.Max(0, math.Min(1, v))*10) / 10
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// fontSize 界面文字在screenWidth*screenHeight下的大小，所有主题都一样
+const fontSize = 24
+
+// theme 主题只决定颜色。血池和传送阵的图块由颜色生成，字体由语言决定，不随主题改变
+type theme struct {
+	Name       string
+	Background color.Color
+	Text       color.Color
+	TextDim    color.Color
+	Grid       color.Color
+	SlipFloor  color.Color
+	Teleporter color.Color
+	CardBack   color.Color
+	CardFace   color.Color
+	CardText   color.Color
+	Death      color.Color
+	Exit       color.Color
+	Highlight  color.Color
+	Players    []color.Color
+
+	imgSlipFloor *ebiten.Image
+	imgTransfer  *ebiten.Image
+}
+
+var themes = []*theme{
+	{
+		Name:       "经典",
+		Background: color.White,
+		Text:       color.Black,
+		TextDim:    color.Gray{Y: 96},
+		Grid:       color.Black,
+		SlipFloor:  colornames.Darkred,
+		Teleporter: colornames.Mediumpurple,
+		CardBack:   colornames.Darkslategray,
+		CardFace:   colornames.Antiquewhite,
+		CardText:   colornames.Darkred,
+		Death:      colornames.Red,
+		Exit:       colornames.Limegreen,
+		Highlight:  color.Black,
+		Players:    []color.Color{colornames.Red, colornames.Green, colornames.Yellow, colornames.Blue, colornames.Purple, colornames.Orange},
+	},
+	{
+		Name:       "夜间",
+		Background: color.RGBA{R: 0x1e, G: 0x1e, B: 0x24, A: 0xff},
+		Text:       color.Gray{Y: 230},
+		TextDim:    color.Gray{Y: 150},
+		Grid:       color.Gray{Y: 110},
+		SlipFloor:  color.RGBA{R: 0x9b, G: 0x1b, B: 0x1b, A: 0xff},
+		Teleporter: color.RGBA{R: 0x6a, G: 0x4c, B: 0xb0, A: 0xff},
+		CardBack:   color.RGBA{R: 0x3a, G: 0x4a, B: 0x5a, A: 0xff},
+		CardFace:   color.Gray{Y: 60},
+		CardText:   colornames.Orangered,
+		Death:      colornames.Orangered,
+		Exit:       colornames.Springgreen,
+		Highlight:  color.White,
+		Players:    []color.Color{colornames.Tomato, colornames.Lime, colornames.Gold, colornames.Deepskyblue, colornames.Violet, colornames.Orange},
+	},
+}
+
+// colorblindPlayers 是Okabe-Ito配色，红绿色盲也能分清
+var colorblindPlayers = []color.Color{
+	color.RGBA{R: 0xd5, G: 0x5e, B: 0x00, A: 0xff},
+	color.RGBA{R: 0x56, G: 0xb4, B: 0xe9, A: 0xff},
+	color.RGBA{R: 0xf0, G: 0xe4, B: 0x42, A: 0xff},
+	color.RGBA{R: 0x00, G: 0x72, B: 0xb2, A: 0xff},
+	color.RGBA{R: 0xcc, G: 0x79, B: 0xa7, A: 0xff},
+	color.RGBA{R: 0x00, G: 0x9e, B: 0x73, A: 0xff},
+}
+
+// playerShapes 画在棋子右上角，不看颜色也能分清是哪个玩家的
+var playerShapes = []string{"●", "■", "▲", "◆", "★", "◎"}
+
+func playerColor(i int) color.Color {
+	if gameSettings.Colorblind {
+		return colorblindPlayers[i]
+	}
+	return currentTheme().Players[i]
+}
+
+// view 是实际的屏幕像素大小，scale是相对于screenWidth*screenHeight的缩放比例
+type view struct {
+	width, height int
+	scale         float64
+	theme         *theme
+	locale        *locale
+	highContrast  bool
+}
+
+var theView = &view{}
+
+var (
+	fontFaces   = make(map[int]font.Face)
+	spriteCache = make(map[spriteKey]*ebiten.Image)
+)
+
+func currentTheme() *theme {
+	for _, t := range themes {
+		if t.Name == gameSettings.Theme {
+			return t
+		}
+	}
+	return themes[0]
+}
+
+// layoutView 根据窗口大小和DPI计算实际的屏幕大小，在大小、主题或语言改变时重新生成字体和缓存的图片
+func layoutView(outsideWidth, outsideHeight int) (int, int) {
+	s := ebiten.DeviceScaleFactor()
+	w, h := int(float64(outsideWidth)*s), int(float64(outsideHeight)*s)
+	if w <= 0 || h <= 0 {
+		w, h = screenWidth, screenHeight
+	}
+	t, l := currentTheme(), currentLocale()
+	if l != theView.locale {
+		for k, face := range fontFaces {
+			_ = face.Close()
+			delete(fontFaces, k)
+		}
+	}
+	hc := gameSettings.HighContrast
+	if w != theView.width || h != theView.height || t != theView.theme || l != theView.locale || hc != theView.highContrast {
+		theView.width, theView.height, theView.theme, theView.locale, theView.highContrast = w, h, t, l, hc
+		theView.scale = math.Min(float64(w)/screenWidth, float64(h)/screenHeight)
+		applyTheme(t)
+	}
+	return w, h
+}
+
+func applyTheme(t *theme) {
+	if t.imgSlipFloor != nil {
+		t.imgSlipFloor.Dispose()
+		t.imgTransfer.Dispose()
+	}
+	t.imgSlipFloor = floorImage(t.SlipFloor, t.Background)
+	t.imgTransfer = floorImage(t.Teleporter, t.Background)
+	fontAlpha = fontFace(fontSize * theView.scale)
+	fontSmall = fontFace(fontSize * 0.75 * theView.scale)
+	fontNum = fontFace(fontSize * 2 * theView.scale)
+	for k, img := range spriteCache {
+		img.Dispose()
+		delete(spriteCache, k)
+	}
+}
+
+// floorImage 在高对比度模式下给地板加上斜条纹
+func floorImage(c, stripe color.Color) *ebiten.Image {
+	img := image.NewRGBA(image.Rect(0, 0, tileSize, tileSize))
+	for x := 0; x < tileSize; x++ {
+		for y := 0; y < tileSize; y++ {
+			if gameSettings.HighContrast && (x+y)%15 < 4 {
+				img.Set(x, y, stripe)
+			} else {
+				img.Set(x, y, c)
+			}
+		}
+	}
+	return ebiten.NewImageFromImage(img)
+}
+
+func fontFace(size float64) font.Face {
+	key := int(math.Round(size))
+	if key < 1 {
+		key = 1
+	}
+	if face, ok := fontFaces[key]; ok {
+		return face
+	}
+	face, err := newFallbackFace(currentLocale().fonts(), &opentype.FaceOptions{
+		Size:    float64(key),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		panic(err)
+	}
+	fontFaces[key] = face
+	return face
+}
+
+func ui(v float64) float64 {
+	return v * theView.scale
+}
+
+func uiInt(v int) int {
+	return int(math.Round(float64(v) * theView.scale))
+}
+
+type spriteKey struct {
+	c     color.Color
+	shape int
+	step  int
+	next  int
+	moved bool
+	size  int
+}
+
+// pieceSprite 返回画好的棋子图片，大小为size*size，同样的棋子只画一次。
+// 中间的大数字是现在的步数，右下角的小数字是翻面后下一轮的步数
+func pieceSprite(c color.Color, shape, step, next int, moved bool, size int) *ebiten.Image {
+	key := spriteKey{c, shape, step, next, moved, size}
+	if img, ok := spriteCache[key]; ok {
+		return img
+	}
+	img := ebiten.NewImage(size, size)
+	s := float64(size) / tileSize
+	if gameSettings.HighContrast {
+		drawRect(img, 4*s, 4*s, float64(size)-8*s, float64(size)-8*s, contrastColor(c))
+	}
+	text.Draw(img, playerShapes[shape], fontFace(14*s), int(44*s), int(16*s), c)
+	if moved {
+		text.Draw(img, "〇", fontFace(96*s), int(-16*s), int(66*s), c)
+	} else if step >= 1 && step <= 20 {
+		text.Draw(img, string(rune('①'+step-1)), fontFace(48*s), int(6*s), int(46*s), c)
+	}
+	nextText := strconv.Itoa(next)
+	face := fontFace(16 * s)
+	text.Draw(img, nextText, face, size-int(4*s)-font.MeasureString(face, nextText).Ceil(), int(56*s), c)
+	spriteCache[key] = img
+	return img
+}
+
+// contrastColor 返回和c对比最强的黑色或白色
+func contrastColor(c color.Color) color.Color {
+	r, g, b, _ := c.RGBA()
+	if 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) > 0x8000 {
+		return color.Black
+	}
+	return color.White
+}
+
+// drawFrame 画一个空心的方框，线宽为lw
+func drawFrame(screen *ebiten.Image, x, y, w, h, lw float64, c color.Color) {
+	drawRect(screen, x, y, w, lw, c)
+	drawRect(screen, x, y+h-lw, w, lw, c)
+	drawRect(screen, x, y, lw, h, c)
+	drawRect(screen, x+w-lw, y, lw, h, c)
+}
+
+func drawRect(screen *ebiten.Image, x, y, w, h float64, c color.Color) {
+	opt := &ebiten.DrawImageOptions{}
+	opt.GeoM.Scale(w, h)
+	opt.GeoM.Translate(x, y)
+	opt.ColorM.ScaleWithColor(c)
+	screen.DrawImage(emptyImage, opt)
+}
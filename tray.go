@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+const (
+	trayHeight    = 96 // 棋盘下方放起点、终点、墓地三个托盘的区域高度
+	trayPieceSize = 28
+)
+
+type trayKind int
+
+const (
+	trayStart trayKind = iota
+	trayFinish
+	trayDead
+	trayCount
+)
+
+var trayNames = [trayCount]string{"起点", "终点", "墓地"}
+
+// trayOf 返回棋子所在的托盘，棋子在棋盘上时返回false
+func (b *board) trayOf(p *playerItem) (trayKind, bool) {
+	switch p.pos {
+	case b.geo.startPos():
+		return trayStart, true
+	case b.geo.finishPos():
+		return trayFinish, true
+	case b.geo.deadPos():
+		return trayDead, true
+	}
+	return 0, false
+}
+
+// trayRect 第k个托盘在屏幕上的位置
+func (g *geometry) trayRect(k trayKind) (x, y, w, h float64) {
+	gap := ui(10)
+	w = (float64(g.gridLen*g.Width) - gap*float64(trayCount-1)) / float64(trayCount)
+	x = float64(g.edgeX) + float64(k)*(w+gap)
+	y = float64(g.edgeY+g.gridLen*g.Height) + ui(30)
+	return x, y, w, ui(trayHeight)
+}
+
+// trayArea 托盘中放棋子的区域，上面留出写托盘名字的地方
+func (g *geometry) trayArea(k trayKind) (x, y, w, h float64) {
+	x, y, w, h = g.trayRect(k)
+	return x + ui(4), y + ui(28), w - ui(8), h - ui(32)
+}
+
+// traySlotSize 托盘里棋子的大小和每行放几个。棋子多的时候缩小，保证最满的托盘里每个棋子都在框里
+func (b *board) traySlotSize() (size, perRow int) {
+	var counts [trayCount]int
+	most := 0
+	for _, p := range b.player {
+		for _, item := range p.items {
+			if k, ok := b.trayOf(item); ok {
+				counts[k]++
+				if counts[k] > most {
+					most = counts[k]
+				}
+			}
+		}
+	}
+	_, _, w, h := b.geo.trayArea(trayStart)
+	for size = uiInt(trayPieceSize); size > 1; size-- {
+		perRow = int(w) / size
+		if perRow >= 1 && perRow*(int(h)/size) >= most {
+			break
+		}
+	}
+	if perRow < 1 {
+		perRow = 1
+	}
+	return size, perRow
+}
+
+// traySlots 托盘中每个棋子的位置，按玩家和棋子的顺序依次排开，位置用格子坐标表示，这样可以和棋盘上的棋子一样做动画
+func (b *board) traySlots() map[*playerItem][2]float64 {
+	g := b.geo
+	slots := make(map[*playerItem][2]float64)
+	var counts [trayCount]int
+	size, perRow := b.traySlotSize()
+	for _, p := range b.player {
+		for _, item := range p.items {
+			k, ok := b.trayOf(item)
+			if !ok {
+				continue
+			}
+			x, y, _, _ := g.trayArea(k)
+			n := counts[k]
+			counts[k]++
+			sx := x + float64(n%perRow*size)
+			sy := y + float64(n/perRow*size)
+			slots[item] = [2]float64{(sx - g.cellX(0)) / float64(g.gridLen), (sy - g.cellY(0)) / float64(g.gridLen)}
+		}
+	}
+	return slots
+}
+
+// pieceSize 棋子画出来的大小由动画中的位置决定：画在托盘的区域里时用托盘里的大小，
+// 在棋盘和托盘之间飞的时候还是格子的大小，这样被吃掉或逃出的棋子飞到托盘时才变小
+func (b *board) pieceSize(p *playerItem) int {
+	g := b.geo
+	_, ay := p.drawn()
+	if _, top, _, _ := g.trayRect(trayStart); g.cellY(ay) >= top {
+		size, _ := b.traySlotSize()
+		return size
+	}
+	return g.gridLen
+}
+
+// pieceRect 棋子画在屏幕上的位置和大小
+func (b *board) pieceRect(p *playerItem) (x, y, size float64) {
+	ax, ay := p.drawn()
+	return b.geo.cellX(ax), b.geo.cellY(ay), float64(b.pieceSize(p))
+}
+
+// piecesAt 返回画在屏幕上(x, y)处的所有棋子
+func (b *board) piecesAt(x, y int) []*playerItem {
+	var items []*playerItem
+	for _, p := range b.player {
+		for _, item := range p.items {
+			px, py, size := b.pieceRect(item)
+			if float64(x) >= px && float64(x) < px+size && float64(y) >= py && float64(y) < py+size {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func (b *board) drawTrays(screen *ebiten.Image) {
+	if len(b.player) == 0 {
+		return
+	}
+	t := theView.theme
+	var counts [trayCount]int
+	for _, p := range b.player {
+		for _, item := range p.items {
+			if k, ok := b.trayOf(item); ok {
+				counts[k]++
+			}
+		}
+	}
+	for k := trayStart; k < trayCount; k++ {
+		x, y, w, h := b.geo.trayRect(k)
+		drawFrame(screen, x, y, w, h, ui(1), t.Grid)
+		text.Draw(screen, fmt.Sprintf("%s　%d", tr(trayNames[k]), counts[k]), fontSmall, int(x+ui(6)), int(y+ui(20)), t.Text)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testTrayBoard 每个玩家有n个棋子，全部放在起点
+func testTrayBoard(g *geometry, players, n int) *board {
+	b := newEmptyBoard(g)
+	for i := 0; i < players; i++ {
+		p := &player{shape: i}
+		for j := 0; j < n; j++ {
+			item := &playerItem{id: fmt.Sprintf("%c%d", 'A'+i, j+1), step: 1, pos: g.startPos(), shape: i}
+			p.items = append(p.items, item)
+		}
+		b.player = append(b.player, p)
+	}
+	b.updateAnim()
+	return b
+}
+
+// withScale 测试时没有窗口，手动设置界面的缩放比例
+func withScale(t *testing.T, scale float64) {
+	old := theView.scale
+	theView.scale = scale
+	t.Cleanup(func() { theView.scale = old })
+}
+
+func TestTraySlotsInsideTray(t *testing.T) {
+	// 自定义规则每人最多9个棋子
+	for _, scale := range []float64{1, 1.5} {
+		withScale(t, scale)
+		for _, g := range []*geometry{newGeometry(15, 10, 3), newGeometry(8, 6, 2), newGeometry(20, 14, 4)} {
+			for _, n := range []int{3, 6, 9} {
+				t.Run(fmt.Sprintf("缩放%g，%dx%d每人%d个", scale, g.Width, g.Height, n), func(t *testing.T) {
+					b := testTrayBoard(g, maxPlayers, n)
+					tx, ty, tw, th := g.trayRect(trayStart)
+					for _, p := range b.player {
+						for _, item := range p.items {
+							x, y, size := b.pieceRect(item)
+							if size < 1 || x < tx || y < ty || x+size > tx+tw || y+size > ty+th {
+								t.Fatalf("%s画在(%.0f,%.0f)，大小%.0f，超出了托盘(%.0f,%.0f,%.0f,%.0f)", item.id, x, y, size, tx, ty, tw, th)
+							}
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestPieceSizeFollowsAnimation(t *testing.T) {
+	withScale(t, 1)
+	g := newGeometry(15, 10, 3)
+	b := testTrayBoard(g, 2, 3)
+	item := b.player[0].items[0]
+	traySize, _ := b.traySlotSize()
+	if size := b.pieceSize(item); size != traySize {
+		t.Fatalf("托盘里的棋子大小是%d，应该是%d", size, traySize)
+	}
+	item.pos = point{5, 5}
+	item.teleport(item.pos)
+	if size := b.pieceSize(item); size != g.gridLen {
+		t.Fatalf("棋盘上的棋子大小是%d", size)
+	}
+	// 被吃掉后还没飞到墓地时保持格子的大小
+	item.pos = g.deadPos()
+	if size := b.pieceSize(item); size != g.gridLen {
+		t.Fatalf("飞向墓地的棋子大小是%d", size)
+	}
+	slot := b.traySlots()[item]
+	item.x, item.y = slot[0], slot[1]
+	if size := b.pieceSize(item); size != traySize {
+		t.Fatalf("到了墓地的棋子大小是%d，应该是%d", size, traySize)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// tutorialStep 教程的一步。piece不为空时要把这个棋子移动到target并确定才能进入下一步，
+// done不为nil时满足条件后进入下一步，否则按Enter键进入下一步
+type tutorialStep struct {
+	lines     []string
+	actions   []action
+	piece     string
+	target    point
+	highlight []point
+	explain   bool
+	done      func(b *board) bool
+}
+
+// tutorial 在固定的棋盘上一步一步地教新玩家推石头、血池和怪物转向的规则，每一步只能做指定的操作
+type tutorial struct {
+	*board
+	steps    []*tutorialStep
+	step     int
+	wrong    bool
+	accepted bool
+	finished bool
+}
+
+var arrowActions = []action{actionUp, actionDown, actionLeft, actionRight, actionConfirm, actionCancel}
+
+// tutorialPuzzle 教程用的棋盘：A1前面有石头，A2前面是血池，A3就在出口旁边，怪物在右边朝左
+func tutorialPuzzle() *puzzle {
+	return &puzzle{
+		Name: "教程",
+		Layout: layout{
+			Geometry:    *newGeometry(15, 10, 3),
+			Stones:      []point{{4, 5}, {10, 1}},
+			SlipFloors:  []point{{5, 8}, {6, 8}, {7, 8}},
+			Monster:     point{12, 5},
+			MonsterFace: left,
+		},
+		Pieces: [][]puzzlePiece{{
+			{Step: 3, Pos: point{3, 5}},
+			{Step: 1, Pos: point{4, 8}},
+			{Step: 2, Pos: point{14, 8}},
+		}},
+		Card: card{Text: "4", Step: 4, Kills: 99},
+	}
+}
+
+func tutorialSteps(g *geometry) []*tutorialStep {
+	return []*tutorialStep{
+		{
+			lines:   []string{"欢迎来到教程！", "你要让棋子从左上角的入口走到右下角的出口，同时躲开怪物。", "按Enter键继续"},
+			actions: []action{actionConfirm},
+		},
+		{
+			lines:   []string{"每个棋子一次要走的步数就是它上面的数字。", "按3键选择A1"},
+			actions: []action{actionPiece(3)},
+			done: func(b *board) bool {
+				return b.pickedPlayerItem != nil && b.pickedPlayerItem.id == "A1"
+			},
+		},
+		{
+			lines:     []string{"棋子可以推动前面的石头，石头后面有东西时推不动。", "按方向键向右走三步，把石头推过去，停在高亮的格子上，然后按Enter键确定。", "走错了可以按Esc键撤销"},
+			actions:   append([]action{actionPiece(3)}, arrowActions...),
+			piece:     "A1",
+			target:    point{6, 5},
+			highlight: []point{{6, 5}},
+		},
+		{
+			lines:     []string{"红色的格子是血池，走上去会一直滑到血池外面，滑的时候不算步数。", "按1键选择A2，向右走一步，然后按Enter键确定"},
+			actions:   append([]action{actionPiece(1)}, arrowActions...),
+			piece:     "A2",
+			target:    point{8, 8},
+			highlight: []point{{5, 8}, {6, 8}, {7, 8}, {8, 8}},
+		},
+		{
+			lines:     []string{"从出口走出棋盘的棋子就逃出了。", "按2键选择A3，先向下再向右走出出口，然后按Enter键确定"},
+			actions:   append([]action{actionPiece(2)}, arrowActions...),
+			piece:     "A3",
+			target:    g.finishPos(),
+			highlight: []point{g.Exit},
+		},
+		{
+			lines:   []string{"所有棋子都走完后，怪物抽一张牌，按牌上的步数移动。", "怪物每走一步都会转向看得到的最近的棋子，旁边的框解释了它为什么这样走"},
+			explain: true,
+			done: func(b *board) bool {
+				return b.bigTurn >= 2 && !b.monsterBusy()
+			},
+		},
+		{
+			lines:   []string{"石头挡住了怪物看A1的视线，所以它一直向左走，", "最后一步看到了下面的A2，转向了下面。被怪物撞到的棋子会被吃掉。", "按Enter键结束教程"},
+			actions: []action{actionConfirm},
+			explain: true,
+		},
+	}
+}
+
+func newTutorial() *tutorial {
+	b := newPuzzleBoard(tutorialPuzzle())
+	b.puzzle = nil
+	t := &tutorial{board: b, steps: tutorialSteps(b.geo)}
+	b.tutorial = t
+	t.enter()
+	return t
+}
+
+func (t *tutorial) current() *tutorialStep {
+	return t.steps[t.step]
+}
+
+// enter 进入当前这一步，只允许这一步需要的操作
+func (t *tutorial) enter() {
+	s := t.current()
+	t.wrong, t.accepted = false, false
+	t.explain = s.explain
+	inputFilter = func(a action) bool {
+		switch a {
+		case actionMute, actionInfo, actionExplain:
+			return true
+		}
+		for _, allowed := range s.actions {
+			if a == allowed {
+				return true
+			}
+		}
+		return false
+	}
+	logger.WithField("step", t.step).Info("tutorial step")
+}
+
+// allowsPiece 这一步能不能选择p，不在教程中时总是可以
+func (t *tutorial) allowsPiece(p *playerItem) bool {
+	return t == nil || t.current().piece == p.id
+}
+
+// accepts 这一步能不能确定p的移动，只有走到目标格子才可以
+func (t *tutorial) accepts(p *playerItem) bool {
+	if t == nil {
+		return true
+	}
+	s := t.current()
+	t.wrong = s.piece != p.id || p.pos != s.target
+	t.accepted = !t.wrong
+	return t.accepted
+}
+
+func (t *tutorial) Update() error {
+	s := t.current()
+	confirmed := isJustPressed(actionConfirm)
+	if err := t.board.Update(); err != nil {
+		return err
+	}
+	var done bool
+	switch {
+	case s.piece != "":
+		done = t.accepted
+	case s.done != nil:
+		done = s.done(t.board)
+	default:
+		done = confirmed
+	}
+	if !done {
+		return nil
+	}
+	if t.step == len(t.steps)-1 {
+		t.finished = true
+		inputFilter = nil
+		logger.Info("tutorial finished")
+		return nil
+	}
+	t.step++
+	t.enter()
+	return nil
+}
+
+func (t *tutorial) Draw(screen *ebiten.Image) {
+	t.board.Draw(screen)
+	th := theView.theme
+	g := t.geo
+	s := t.current()
+	for _, p := range s.highlight {
+		if t.tick/20%2 == 0 {
+			drawFrame(screen, g.cellX(float64(p.x)), g.cellY(float64(p.y)), float64(g.gridLen), float64(g.gridLen), ui(4), th.Exit)
+		}
+	}
+	lines := []textPart{{trf("教程 %d/%d", t.step+1, len(t.steps)), th.TextDim}}
+	for _, line := range s.lines {
+		lines = append(lines, textPart{tr(line), th.Text})
+	}
+	if t.wrong {
+		lines = append(lines, textPart{tr("要停在高亮的格子上，按Esc键撤销再试一次"), th.Death})
+	}
+	drawTextBox(screen, uiInt(20), theView.height, lines)
+}